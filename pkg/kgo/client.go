@@ -182,7 +182,14 @@ func NewClient(opts ...Opt) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	if compressor != nil && cfg.compressionCache > 0 {
+		compressor.cache = newCompressCache(cfg.compressionCache)
+	}
+	if compressor != nil {
+		compressor.minSize = cfg.minCompressionSize
+	}
 	cl.compressor = compressor
+	cl.decompressor.snappyFramedFallback = cfg.snappyFramedFallback
 
 	// Before we start any goroutines below, we must notify any interested
 	// hooks of our existence.
@@ -239,6 +246,65 @@ func (cl *Client) Ping(ctx context.Context) error {
 	return lastErr
 }
 
+// ProbeGroupCoordinator returns whether the coordinator for the client's
+// group can be found and reached, by issuing a FindCoordinator request
+// (retrying as necessary until the context is done) and then an ApiVersions
+// request to the coordinator. This returns an error if the client is not
+// consuming as a group.
+//
+// If the coordinator cannot be found, the group management goroutine quietly
+// retries with backoff, and polling returns no records and no errors. This
+// function can be used at startup or in health checks, with a context
+// deadline, to surface a misconfiguration immediately.
+func (cl *Client) ProbeGroupCoordinator(ctx context.Context) error {
+	if cl.consumer.g == nil {
+		return errNotGroup
+	}
+	group := cl.cfg.group
+
+	b, err := cl.loadCoordinator(ctx, coordinatorKey{
+		name: group,
+		typ:  coordinatorTypeGroup,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to find coordinator for group %q: %w", group, err)
+	}
+
+	req := kmsg.NewPtrApiVersionsRequest()
+	req.ClientSoftwareName = cl.cfg.softwareName
+	req.ClientSoftwareVersion = cl.cfg.softwareVersion
+	if _, err := b.waitResp(ctx, req); err != nil {
+		return fmt.Errorf("unable to reach coordinator %d for group %q: %w", b.meta.NodeID, group, err)
+	}
+	return nil
+}
+
+// GroupCoordinator returns the broker that is the coordinator for the group
+// this client is consuming as, which is where group requests (heartbeats,
+// commits, etc.) are routed. This is useful for troubleshooting, e.g. to know
+// which broker's logs to check for group problems.
+//
+// The coordinator is cached after it is first loaded and is only reloaded
+// once a group request fails with an error indicating the coordinator has
+// moved, meaning this usually returns immediately. This returns an error if
+// the client is not consuming as a group or if the coordinator cannot be
+// loaded.
+func (cl *Client) GroupCoordinator(ctx context.Context) (BrokerMetadata, error) {
+	if cl.consumer.g == nil {
+		return BrokerMetadata{}, errNotGroup
+	}
+	group := cl.cfg.group
+
+	b, err := cl.loadCoordinator(ctx, coordinatorKey{
+		name: group,
+		typ:  coordinatorTypeGroup,
+	})
+	if err != nil {
+		return BrokerMetadata{}, fmt.Errorf("unable to find coordinator for group %q: %w", group, err)
+	}
+	return b.meta, nil
+}
+
 // Parse broker IP/host and port from a string, using the default Kafka port if
 // unspecified. Supported address formats:
 //
@@ -611,6 +677,11 @@ func (cl *Client) Close() {
 // configuration, and it will rewrite the timeout millis if the acks is 0. It
 // is strongly recommended to not issue raw kmsg.ProduceRequest's.
 func (cl *Client) Request(ctx context.Context, req kmsg.Request) (kmsg.Response, error) {
+	if fn := cl.cfg.interceptRequest; fn != nil {
+		if resp, err := fn(ctx, req); resp != nil || err != nil {
+			return resp, err
+		}
+	}
 	resps, merge := cl.shardedRequest(ctx, req)
 	// If there is no merge function, only one request was issued directly
 	// to a broker. Return the resp and err directly.
@@ -1,7 +1,12 @@
 package kgo
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kmsg"
 )
 
 func TestParseBrokerAddr(t *testing.T) {
@@ -93,3 +98,63 @@ func TestParseBrokerAddrErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestInterceptRequests(t *testing.T) {
+	var intercepted int
+	cl, err := NewClient(
+		SeedBrokers("127.0.0.1:1"),
+		InterceptRequests(func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+			if _, ok := req.(*kmsg.HeartbeatRequest); !ok {
+				return nil, nil
+			}
+			intercepted++
+			resp := kmsg.NewPtrHeartbeatResponse()
+			resp.ErrorCode = kerr.RebalanceInProgress.Code
+			return resp, nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	req := kmsg.NewPtrHeartbeatRequest()
+	req.Group = "group"
+	resp, err := req.RequestWith(context.Background(), cl)
+	if err != nil {
+		t.Fatalf("unexpected request error: %v", err)
+	}
+	if err := kerr.ErrorForCode(resp.ErrorCode); err != kerr.RebalanceInProgress {
+		t.Errorf("got resp error %v, expected %v", err, kerr.RebalanceInProgress)
+	}
+	if intercepted != 1 {
+		t.Errorf("got %d intercepted requests, expected 1", intercepted)
+	}
+}
+
+func TestGroupTimeoutValidation(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		opts   []Opt
+		expErr bool
+	}{
+		{"defaults", nil, false},
+		{"heartbeat below a third of the session", []Opt{SessionTimeout(9 * time.Second), HeartbeatInterval(3*time.Second - time.Millisecond)}, false},
+		{"heartbeat at a third of the session", []Opt{SessionTimeout(9 * time.Second), HeartbeatInterval(3 * time.Second)}, true},
+		{"rebalance at the session", []Opt{SessionTimeout(45 * time.Second), RebalanceTimeout(45 * time.Second)}, false},
+		{"rebalance below the session", []Opt{SessionTimeout(45 * time.Second), RebalanceTimeout(30 * time.Second)}, true},
+		{"skipped validation", []Opt{SessionTimeout(9 * time.Second), HeartbeatInterval(5 * time.Second), SkipGroupTimeoutValidation()}, false},
+		{"skipped validation, rebalance below the session", []Opt{SessionTimeout(45 * time.Second), RebalanceTimeout(30 * time.Second), SkipGroupTimeoutValidation()}, false},
+		{"skipped validation, heartbeat at the session", []Opt{SessionTimeout(9 * time.Second), HeartbeatInterval(9 * time.Second), SkipGroupTimeoutValidation()}, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cl, err := NewClient(append([]Opt{SeedBrokers("127.0.0.1:1"), ConsumerGroup("group"), ConsumeTopics("t")}, test.opts...)...)
+			if err == nil {
+				cl.Close()
+			}
+			if gotErr := err != nil; gotErr != test.expErr {
+				t.Errorf("got err %v, expected err? %v", err, test.expErr)
+			}
+		})
+	}
+}
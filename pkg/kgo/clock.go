@@ -0,0 +1,38 @@
+package kgo
+
+import "time"
+
+// clock abstracts time for the group management goroutines so that tests can
+// drive heartbeating, autocommitting, and backoff deterministically. The
+// client always uses the real clock unless overridden with withClock.
+type clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) ticker
+	After(d time.Duration) <-chan time.Time
+}
+
+// ticker is the subset of *time.Ticker that we use.
+type ticker interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) NewTicker(d time.Duration) ticker       { return realTicker{time.NewTicker(d)} }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time   { return t.t.C }
+func (t realTicker) Reset(d time.Duration) { t.t.Reset(d) }
+func (t realTicker) Stop()                 { t.t.Stop() }
+
+// withClock overrides the clock used for group heartbeating, autocommitting,
+// rebalance waits, and group backoffs and retries. This is only meant for
+// tests.
+func withClock(c clock) Opt {
+	return clientOpt{func(cfg *cfg) { cfg.clock = c }}
+}
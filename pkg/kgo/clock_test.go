@@ -0,0 +1,119 @@
+package kgo
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock is a clock that only moves when Advance is called, allowing tests
+// to step group timers deterministically.
+type fakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	timers  []fakeTimer
+	tickers []*fakeTicker
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+type fakeTicker struct {
+	clock *fakeClock
+	d     time.Duration
+	next  time.Time
+	c     chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{now: time.Unix(0, 0)}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, fakeTimer{c.now.Add(d), ch})
+	c.cond.Broadcast()
+	return ch
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{c, d, c.now.Add(d), make(chan time.Time, 1)}
+	c.tickers = append(c.tickers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker that
+// is due. Like a real ticker, a ticker whose channel is full drops ticks.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+
+	keep := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			keep = append(keep, t)
+			continue
+		}
+		t.c <- t.at
+	}
+	c.timers = keep
+
+	for _, t := range c.tickers {
+		for !t.next.After(c.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.d)
+		}
+	}
+}
+
+// waitPending blocks until at least n timers and tickers are pending.
+func (c *fakeClock) waitPending(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers)+len(c.tickers) < n {
+		c.cond.Wait()
+	}
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.d = d
+	t.next = t.clock.now.Add(d)
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, other := range t.clock.tickers {
+		if other == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
@@ -3,8 +3,10 @@ package kgo
 import (
 	"bytes"
 	"compress/gzip"
+	"container/list"
 	"encoding/binary"
 	"errors"
+	"hash/fnv"
 	"io/ioutil"
 	"runtime"
 	"sync"
@@ -50,6 +52,10 @@ func GzipCompression() CompressionCodec { return CompressionCodec{1, gzip.Defaul
 func SnappyCompression() CompressionCodec { return CompressionCodec{2, 0} }
 
 // Lz4Compression enables lz4 compression with the fastest compression level.
+//
+// Levels 1 through 9 (see WithLevel) use lz4's high compression (HC) mode,
+// trading CPU for a better compression ratio; any other level uses the fast
+// mode.
 func Lz4Compression() CompressionCodec { return CompressionCodec{3, 0} }
 
 // ZstdCompression enables zstd compression with the default compression level.
@@ -70,11 +76,23 @@ func (c CompressionCodec) WithLevel(level int) CompressionCodec {
 	return c
 }
 
+// lz4Level maps a codec level to an lz4 level: 1 through 9 are the lz4 high
+// compression levels, and anything else is the fast level.
+func lz4Level(level int8) lz4.CompressionLevel {
+	if level < 1 || level > 9 {
+		return lz4.Fast
+	}
+	return lz4.Level1 << uint(level-1)
+}
+
 type compressor struct {
 	options  []int8
 	gzPool   sync.Pool
 	lz4Pool  sync.Pool
 	zstdPool sync.Pool
+
+	cache   *compressCache // nil unless ProducerBatchCompressionCache
+	minSize int            // payloads smaller than this are not compressed
 }
 
 func newCompressor(codecs ...CompressionCodec) (*compressor, error) {
@@ -115,14 +133,11 @@ out:
 			}
 			c.gzPool = sync.Pool{New: func() interface{} { c, _ := gzip.NewWriterLevel(nil, int(level)); return c }}
 		case 3:
-			level := codec.level
-			if level < 0 {
-				level = 0
-			}
+			level := lz4Level(codec.level)
 			c.lz4Pool = sync.Pool{
 				New: func() interface{} {
 					w := lz4.NewWriter(new(bytes.Buffer))
-					if err := w.Apply(lz4.CompressionLevelOption(lz4.CompressionLevel(level))); err != nil {
+					if err := w.Apply(lz4.CompressionLevelOption(level)); err != nil {
 						w.Close()
 						w = lz4.NewWriter(nil)
 					}
@@ -169,7 +184,7 @@ type zstdEncoder struct {
 //
 // The writer should be put back to its pool after the returned slice is done
 // being used.
-func (c *compressor) compress(dst *sliceWriter, src []byte, produceRequestVersion int16) ([]byte, int8) {
+func (c *compressor) compress(dst *sliceWriter, src []byte, produceRequestVersion int16) (compressed []byte, codec int8) {
 	dst.inner = dst.inner[:0]
 
 	var use int8
@@ -181,9 +196,21 @@ func (c *compressor) compress(dst *sliceWriter, src []byte, produceRequestVersio
 		break
 	}
 
-	switch use {
-	case 0:
+	if use == 0 || len(src) < c.minSize {
 		return src, 0
+	}
+	if c.cache != nil {
+		if cached, ok := c.cache.get(use, src); ok {
+			return cached, use
+		}
+		defer func() {
+			if compressed != nil {
+				c.cache.put(use, src, compressed)
+			}
+		}()
+	}
+
+	switch use {
 	case 1:
 		gz := c.gzPool.Get().(*gzip.Writer)
 		defer c.gzPool.Put(gz)
@@ -217,10 +244,96 @@ func (c *compressor) compress(dst *sliceWriter, src []byte, produceRequestVersio
 	return dst.inner, int8(use)
 }
 
+// compressCache is a small LRU of compressed payloads. Entries are keyed by the
+// codec used and a hash of the uncompressed payload, and the full payload is
+// compared before returning a hit.
+type compressCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[compressCacheKey]*list.Element
+	lru     *list.List // front is most recently used
+}
+
+type compressCacheKey struct {
+	codec int8
+	hash  uint64
+}
+
+type compressCacheEntry struct {
+	key        compressCacheKey
+	src        []byte
+	compressed []byte
+}
+
+func newCompressCache(max int) *compressCache {
+	return &compressCache{
+		max:     max,
+		entries: make(map[compressCacheKey]*list.Element, max),
+		lru:     list.New(),
+	}
+}
+
+func compressCacheHash(codec int8, src []byte) compressCacheKey {
+	h := fnv.New64a()
+	h.Write(src)
+	return compressCacheKey{codec, h.Sum64()}
+}
+
+// get returns the cached compressed bytes for src. The returned slice must
+// not be modified.
+func (c *compressCache) get(codec int8, src []byte) ([]byte, bool) {
+	key := compressCacheHash(codec, src)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, exists := c.entries[key]
+	if !exists {
+		return nil, false
+	}
+	entry := e.Value.(*compressCacheEntry)
+	if !bytes.Equal(entry.src, src) {
+		return nil, false // hash collision
+	}
+	c.lru.MoveToFront(e)
+	return entry.compressed, true
+}
+
+// put caches copies of src and compressed, evicting the least recently used
+// entry if the cache is full.
+func (c *compressCache) put(codec int8, src, compressed []byte) {
+	key := compressCacheHash(codec, src)
+	entry := &compressCacheEntry{
+		key:        key,
+		src:        append([]byte(nil), src...),
+		compressed: append([]byte(nil), compressed...),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, exists := c.entries[key]; exists {
+		e.Value = entry
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	if c.lru.Len() > c.max {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*compressCacheEntry).key)
+	}
+}
+
 type decompressor struct {
 	ungzPool   sync.Pool
 	unlz4Pool  sync.Pool
 	unzstdPool sync.Pool
+
+	// snappyFramedFallback, if true, retries decoding snappy data that is
+	// not xerial framed and fails a raw block decode as the snappy framing
+	// (stream) format.
+	snappyFramedFallback bool
 }
 
 func newDecompressor() *decompressor {
@@ -267,13 +380,20 @@ func (d *decompressor) decompress(src []byte, codec byte) ([]byte, error) {
 		if len(src) > 16 && bytes.HasPrefix(src, xerialPfx) {
 			return xerialDecode(src)
 		}
-		return s2.Decode(nil, src)
+		dst, err := s2.Decode(nil, src)
+		if err != nil && d.snappyFramedFallback && bytes.HasPrefix(src, snappyFramedPfx) {
+			return ioutil.ReadAll(s2.NewReader(bytes.NewReader(src)))
+		}
+		return dst, err
 	case 3:
 		unlz4 := d.unlz4Pool.Get().(*lz4.Reader)
 		defer d.unlz4Pool.Put(unlz4)
 		unlz4.Reset(bytes.NewReader(src))
 		return ioutil.ReadAll(unlz4)
 	case 4:
+		// DecodeAll decodes every frame in src, meaning producers
+		// that concatenate multiple zstd frames into one batch are
+		// fully decompressed.
 		unzstd := d.unzstdPool.Get().(*zstdDecoder)
 		defer d.unzstdPool.Put(unzstd)
 		return unzstd.inner.DecodeAll(src, nil)
@@ -284,6 +404,10 @@ func (d *decompressor) decompress(src []byte, codec byte) ([]byte, error) {
 
 var xerialPfx = []byte{130, 83, 78, 65, 80, 80, 89, 0}
 
+// snappyFramedPfx is the stream identifier chunk that begins any data in the
+// snappy framing format: chunk type 0xff, a three byte length of 6, "sNaPpY".
+var snappyFramedPfx = []byte{255, 6, 0, 0, 115, 78, 97, 80, 112, 89}
+
 var errMalformedXerial = errors.New("malformed xerial framing")
 
 func xerialDecode(src []byte) ([]byte, error) {
@@ -94,6 +94,148 @@ func TestCompressDecompress(t *testing.T) {
 	wg.Wait()
 }
 
+func TestCompressNegotiatesProduceVersion(t *testing.T) {
+	in := []byte("foo")
+	for _, test := range []struct {
+		codecs         []CompressionCodec
+		produceVersion int16
+		exp            int8
+	}{
+		{[]CompressionCodec{ZstdCompression(), SnappyCompression()}, 7, 4},
+		{[]CompressionCodec{ZstdCompression(), SnappyCompression()}, 6, 2},
+		{[]CompressionCodec{ZstdCompression(), Lz4Compression(), SnappyCompression()}, 3, 3},
+		{[]CompressionCodec{ZstdCompression()}, 6, 0},
+		{[]CompressionCodec{ZstdCompression(), NoCompression()}, 6, 0},
+	} {
+		c, _ := newCompressor(test.codecs...)
+		w := sliceWriters.Get().(*sliceWriter)
+		_, used := c.compress(w, in, test.produceVersion)
+		sliceWriters.Put(w)
+		if used != test.exp {
+			t.Errorf("codecs %v at produce version %d: got codec %d != exp %d", test.codecs, test.produceVersion, used, test.exp)
+		}
+	}
+}
+
+func TestLz4HighCompression(t *testing.T) {
+	t.Parallel()
+	in := bytes.Repeat([]byte("high compression lz4 "), 1000)
+	d := newDecompressor()
+	for _, level := range []int{0, 1, 9, 127} {
+		c, err := newCompressor(Lz4Compression().WithLevel(level))
+		if err != nil {
+			t.Fatalf("level %d: unexpected newCompressor err: %v", level, err)
+		}
+		w := sliceWriters.Get().(*sliceWriter)
+		got, used := c.compress(w, in, 7)
+		if used != 3 {
+			t.Errorf("level %d: got codec %d != exp 3", level, used)
+		}
+		got, err = d.decompress(got, byte(used))
+		sliceWriters.Put(w)
+		if err != nil {
+			t.Errorf("level %d: unexpected decompress err: %v", level, err)
+			continue
+		}
+		if !bytes.Equal(got, in) {
+			t.Errorf("level %d: round trip mismatch", level)
+		}
+	}
+}
+
+func TestDecompressConcatenatedZstdFrames(t *testing.T) {
+	t.Parallel()
+	c, _ := newCompressor(ZstdCompression())
+	var in, payload []byte
+	for _, frame := range [][]byte{[]byte("first frame "), []byte("second frame")} {
+		w := sliceWriters.Get().(*sliceWriter)
+		got, _ := c.compress(w, frame, 7)
+		payload = append(payload, got...)
+		sliceWriters.Put(w)
+		in = append(in, frame...)
+	}
+
+	got, err := newDecompressor().decompress(payload, 4)
+	if err != nil {
+		t.Fatalf("unexpected decompress err: %v", err)
+	}
+	if !bytes.Equal(got, in) {
+		t.Errorf("got decompress %s != exp %s", got, in)
+	}
+}
+
+func TestCompressCache(t *testing.T) {
+	t.Parallel()
+	c, _ := newCompressor(ZstdCompression(), SnappyCompression())
+	c.cache = newCompressCache(2)
+	in := bytes.Repeat([]byte("fan out payload "), 100)
+	d := newDecompressor()
+
+	for _, test := range []struct {
+		produceVersion int16
+		exp            int8
+	}{
+		{7, 4},
+		{7, 4}, // cached
+		{6, 2}, // same payload, but the codec differs for older brokers
+		{6, 2}, // cached
+	} {
+		w := sliceWriters.Get().(*sliceWriter)
+		got, used := c.compress(w, in, test.produceVersion)
+		if used != test.exp {
+			t.Errorf("produce version %d: got codec %d != exp %d", test.produceVersion, used, test.exp)
+		}
+		got, err := d.decompress(got, byte(used))
+		sliceWriters.Put(w)
+		if err != nil {
+			t.Errorf("produce version %d: unexpected decompress err: %v", test.produceVersion, err)
+			continue
+		}
+		if !bytes.Equal(got, in) {
+			t.Errorf("produce version %d: round trip mismatch", test.produceVersion)
+		}
+	}
+	if l := c.cache.lru.Len(); l != 2 {
+		t.Errorf("got %d cached entries != exp 2", l)
+	}
+
+	// A third payload evicts the least recently used zstd entry.
+	w := sliceWriters.Get().(*sliceWriter)
+	c.compress(w, []byte("other payload other payload"), 6)
+	sliceWriters.Put(w)
+	if _, ok := c.cache.get(4, in); ok {
+		t.Error("zstd entry unexpectedly still cached")
+	}
+	if _, ok := c.cache.get(2, in); !ok {
+		t.Error("snappy entry unexpectedly evicted")
+	}
+}
+
+func TestMinCompressionSize(t *testing.T) {
+	t.Parallel()
+	c, _ := newCompressor(SnappyCompression())
+	c.minSize = 64
+
+	for _, test := range []struct {
+		in  []byte
+		exp int8
+	}{
+		{[]byte("foo"), 0},
+		{bytes.Repeat([]byte("a"), 63), 0},
+		{bytes.Repeat([]byte("a"), 64), 2},
+	} {
+		w := sliceWriters.Get().(*sliceWriter)
+		got, used := c.compress(w, test.in, 7)
+		if used != test.exp {
+			t.Errorf("len %d: got codec %d != exp %d", len(test.in), used, test.exp)
+		}
+		if used == 0 && !bytes.Equal(got, test.in) {
+			t.Errorf("len %d: uncompressed payload mismatch", len(test.in))
+		}
+		sliceWriters.Put(w)
+	}
+}
+
 func BenchmarkCompress(b *testing.B) {
 	c, _ := newCompressor(CompressionCodec{codec: 2}) // snappy
 	in := []byte("foo")
@@ -154,3 +296,42 @@ func Test_xerialDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestDecompressSnappyFramings(t *testing.T) {
+	t.Parallel()
+	const (
+		xerial = "glNOQVBQWQAAAAABAAAAAQAAAA8NMEhlbGxvLCBXb3JsZCE="
+		raw    = "DTBIZWxsbywgV29ybGQh"
+		framed = "/wYAAHNOYVBwWQERAACChVPDSGVsbG8sIFdvcmxkIQ=="
+	)
+	for _, test := range []struct {
+		name     string
+		input    string
+		fallback bool
+		wantErr  bool
+	}{
+		{name: "xerial", input: xerial},
+		{name: "xerial with fallback", input: xerial, fallback: true},
+		{name: "raw block", input: raw},
+		{name: "raw block with fallback", input: raw, fallback: true},
+		{name: "framed", input: framed, wantErr: true},
+		{name: "framed with fallback", input: framed, fallback: true},
+		{name: "corrupt framed with fallback", input: framed[:len(framed)-8], fallback: true, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := base64.StdEncoding.DecodeString(test.input)
+			if err != nil {
+				t.Fatalf("base64 decode error = %v", err)
+			}
+			d := newDecompressor()
+			d.snappyFramedFallback = test.fallback
+			got, err := d.decompress(data, 2)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("decompress() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, []byte("Hello, World!")) {
+				t.Errorf("got decompress %s != exp Hello, World!", got)
+			}
+		})
+	}
+}
@@ -85,6 +85,8 @@ type cfg struct {
 	retries      int64
 	retryTimeout func(int16) time.Duration
 
+	clock clock // only overridden in tests
+
 	maxBrokerWriteBytes int32
 	maxBrokerReadBytes  int32
 
@@ -97,6 +99,8 @@ type cfg struct {
 
 	hooks hooks
 
+	interceptRequest func(context.Context, kmsg.Request) (kmsg.Response, error)
+
 	//////////////////////
 	// PRODUCER SECTION //
 	//////////////////////
@@ -106,6 +110,8 @@ type cfg struct {
 	acks               Acks
 	disableIdempotency bool
 	compression        []CompressionCodec // order of preference
+	compressionCache   int                // number of compressed payloads to cache, 0 disables
+	minCompressionSize int                // payloads smaller than this are not compressed
 
 	defaultProduceTopic string
 	maxRecordBatchBytes int32
@@ -136,6 +142,9 @@ type cfg struct {
 
 	maxConcurrentFetches int
 	disableFetchSessions bool
+	snappyFramedFallback bool
+	validateBatch        func(topic string, partition int32, decompressed []byte) error
+	consumeCtx           context.Context
 
 	topics     map[string]*regexp.Regexp   // topics to consume; if regex is true, values are compiled regular expressions
 	partitions map[string]map[int32]Offset // partitions to directly consume from
@@ -147,30 +156,70 @@ type cfg struct {
 
 	group      string          // group we are in
 	instanceID *string         // optional group instance ID
+	memberID   string          // optional initial member ID
 	balancers  []GroupBalancer // balancers we can use
-	protocol   string          // "consumer" by default, expected to never be overridden
+	protocol   string          // "consumer" by default, overridden with GroupProtocol
 
 	sessionTimeout    time.Duration
 	rebalanceTimeout  time.Duration
 	heartbeatInterval time.Duration
 	requireStable     bool
 
+	skipGroupTimeoutCheck bool
+
+	maxAssignedPartitions int
+	fetchAssignedSubset   map[string][]int32
+	latestOnFirstJoin     bool
+	skipUnchangedAssigned bool
+	failUnrequestedTopics bool
+
+	stuckRebalanceMultiple float64
+	backgroundRevoke       bool
+
+	rebalanceBackoff   func(int) time.Duration
+	offsetFetchBackoff func(int) time.Duration
+
+	slowCallbackFraction float64
+	revokeTimeout        time.Duration
+
 	onAssigned func(context.Context, *Client, map[string][]int32)
 	onRevoked  func(context.Context, *Client, map[string][]int32)
 	onLost     func(context.Context, *Client, map[string][]int32)
 
+	onRevokedCommit func(context.Context, *Client, map[string][]int32) map[string]map[int32]EpochOffset
+
 	adjustOffsetsBeforeAssign func(ctx context.Context, offsets map[string]map[int32]Offset) (map[string]map[int32]Offset, error)
+	adjustJoinGroupRequest    func(*kmsg.JoinGroupRequest)
+	joinMetadataMaxBytes      int32
+	joinMetadataTrimOwned     bool
+	externalOffsets           func(ctx context.Context, added map[string][]int32) (map[string]map[int32]EpochOffset, error)
+
+	rejoinOnMetadataChange   func(current, proposed map[string]int) bool
+	newPartitionsRejoinDelay time.Duration
+	subscriptionRejoinWindow time.Duration
 
 	setAssigned       bool
 	setRevoked        bool
 	setLost           bool
 	setCommitCallback bool
 
-	autocommitDisable  bool // true if autocommit was disabled or we are transactional
-	autocommitGreedy   bool
-	autocommitMarks    bool
-	autocommitInterval time.Duration
-	commitCallback     func(*Client, *kmsg.OffsetCommitRequest, *kmsg.OffsetCommitResponse, error)
+	autocommitDisable   bool // true if autocommit was disabled or we are transactional
+	autocommitGreedy    bool
+	autocommitMarks     bool
+	autocommitFirstPoll bool
+	autocommitSkipSame  bool
+	commitTracker       func(topic string, partition int32) (offset int64, epoch int32, ok bool)
+	autocommitInterval  time.Duration
+	autocommitTopics    map[string]time.Duration // per-topic interval overrides
+	commitCallback      func(*Client, *kmsg.OffsetCommitRequest, *kmsg.OffsetCommitResponse, error)
+	omitCommitMetadata  bool
+	commitMetadata      *string
+	rejectCommitRewinds bool
+	commitQueueDepth    int
+	commitMaxPartitions int
+	offsetsClient       *Client // if non-nil, commits and offset fetches go here
+
+	autocommitPartitionErrs func(*Client, map[string]map[int32]error) bool
 }
 
 // cooperative is a helper that returns whether all group balancers in the
@@ -298,13 +347,13 @@ func (cfg *cfg) validate() error {
 
 		// Group settings.
 		{name: "number of balancers", v: int64(len(cfg.balancers)), allowed: 1, badcmp: i64lt},
+		{name: "max assigned partitions", v: int64(cfg.maxAssignedPartitions), allowed: 0, badcmp: i64lt},
+		{name: "commit queue depth", v: int64(cfg.commitQueueDepth), allowed: 1, badcmp: i64lt},
 		{name: "consumer protocol length", v: int64(len(cfg.protocol)), allowed: 1, badcmp: i64lt},
 
 		{name: "session timeout", v: int64(cfg.sessionTimeout), allowed: int64(100 * time.Millisecond), badcmp: i64lt, durs: true},
 		{name: "rebalance timeout", v: int64(cfg.rebalanceTimeout), allowed: int64(100 * time.Millisecond), badcmp: i64lt, durs: true},
 		{name: "autocommit interval", v: int64(cfg.autocommitInterval), allowed: int64(100 * time.Millisecond), badcmp: i64lt, durs: true},
-
-		{v: int64(cfg.heartbeatInterval), allowed: int64(cfg.rebalanceTimeout) * int64(time.Millisecond), badcmp: i64gt, durs: true, fmt: "heartbeat interval %v is erroneously larger than the session timeout %v"},
 	} {
 		bad, cmp := limit.badcmp(limit.v, limit.allowed)
 		if bad {
@@ -328,6 +377,16 @@ func (cfg *cfg) validate() error {
 		if len(cfg.partitions) != 0 {
 			return errors.New("invalid direct-partition consuming option when consuming as a group")
 		}
+		if !cfg.skipGroupTimeoutCheck {
+			if cfg.heartbeatInterval >= cfg.sessionTimeout/3 {
+				return fmt.Errorf("heartbeat interval %v is not less than a third of the session timeout %v (see SkipGroupTimeoutValidation)", cfg.heartbeatInterval, cfg.sessionTimeout)
+			}
+			if cfg.rebalanceTimeout < cfg.sessionTimeout {
+				return fmt.Errorf("rebalance timeout %v is less than the session timeout %v (see SkipGroupTimeoutValidation)", cfg.rebalanceTimeout, cfg.sessionTimeout)
+			}
+		} else if cfg.heartbeatInterval >= cfg.sessionTimeout {
+			return fmt.Errorf("heartbeat interval %v is erroneously not less than the session timeout %v", cfg.heartbeatInterval, cfg.sessionTimeout)
+		}
 	}
 
 	if cfg.regex {
@@ -360,12 +419,74 @@ func (cfg *cfg) validate() error {
 	if cfg.autocommitGreedy && cfg.autocommitMarks {
 		return errors.New("cannot enable both greedy autocommitting and marked autocommitting")
 	}
-	if (cfg.autocommitGreedy || cfg.autocommitDisable || cfg.autocommitMarks || cfg.setCommitCallback) && len(cfg.group) == 0 {
+	if cfg.autocommitDisable && cfg.autocommitFirstPoll {
+		return errors.New("cannot both disable autocommitting and enable autocommitting on the first poll")
+	}
+	if cfg.autocommitDisable && cfg.autocommitSkipSame {
+		return errors.New("cannot both disable autocommitting and skip unchanged autocommits")
+	}
+	if cfg.autocommitMarks && cfg.commitTracker != nil {
+		return errors.New("cannot both enable marked autocommitting and set a commit offset tracker")
+	}
+	if cfg.stuckRebalanceMultiple < 0 {
+		return fmt.Errorf("stuck rebalance multiple %v is less than min allowed 0", cfg.stuckRebalanceMultiple)
+	}
+	if cfg.slowCallbackFraction < 0 {
+		return fmt.Errorf("slow callback warn fraction %v is less than min allowed 0", cfg.slowCallbackFraction)
+	}
+	if cfg.revokeTimeout < 0 {
+		return fmt.Errorf("revoke timeout %v is less than min allowed 0", cfg.revokeTimeout)
+	}
+	if cfg.commitMaxPartitions < 0 {
+		return fmt.Errorf("commit max partitions per request %v is less than min allowed 0", cfg.commitMaxPartitions)
+	}
+	if cfg.joinMetadataMaxBytes < 0 {
+		return fmt.Errorf("join group metadata max bytes %v is less than min allowed 0", cfg.joinMetadataMaxBytes)
+	}
+	if cfg.autocommitDisable && len(cfg.autocommitTopics) > 0 {
+		return errors.New("cannot both disable autocommitting and set per-topic autocommit intervals")
+	}
+	for topic, interval := range cfg.autocommitTopics {
+		if interval < 100*time.Millisecond {
+			return fmt.Errorf("autocommit interval %v for topic %q is less than min allowed 100ms", interval, topic)
+		}
+	}
+	if cfg.autocommitDisable && cfg.autocommitPartitionErrs != nil {
+		return errors.New("cannot both disable autocommitting and set an autocommit partition errors function")
+	}
+	if cfg.omitCommitMetadata && cfg.commitMetadata != nil {
+		return errors.New("cannot both omit commit metadata and set commit metadata")
+	}
+	if cfg.txnID != nil && len(cfg.group) > 0 {
+		// Transactional group consumers commit offsets only when
+		// ending transactions; autocommitting is always disabled.
+		for _, opt := range []struct {
+			name string
+			set  bool
+		}{
+			{"GreedyAutoCommit", cfg.autocommitGreedy},
+			{"AutoCommitMarks", cfg.autocommitMarks},
+			{"AutoCommitOnFirstPoll", cfg.autocommitFirstPoll},
+			{"AutoCommitTopicIntervals", len(cfg.autocommitTopics) > 0},
+			{"AutoCommitCallback", cfg.setCommitCallback},
+		} {
+			if opt.set {
+				return fmt.Errorf("invalid autocommit option %s specified with a transactional ID: transactional group consumers commit offsets when ending transactions and never autocommit", opt.name)
+			}
+		}
+	}
+	if (cfg.autocommitGreedy || cfg.autocommitDisable || cfg.autocommitMarks || cfg.autocommitFirstPoll || cfg.setCommitCallback || len(cfg.autocommitTopics) > 0) && len(cfg.group) == 0 {
 		return errors.New("invalid autocommit options specified when a group was not specified")
 	}
-	if (cfg.setLost || cfg.setRevoked || cfg.setAssigned) && len(cfg.group) == 0 {
+	if (cfg.setLost || cfg.setRevoked || cfg.setAssigned || cfg.onRevokedCommit != nil) && len(cfg.group) == 0 {
 		return errors.New("invalid group partition assigned/revoked/lost functions set when a group was not specified")
 	}
+	if cfg.setRevoked && cfg.onRevokedCommit != nil {
+		return errors.New("cannot set both OnPartitionsRevoked and OnPartitionsRevokedCommit")
+	}
+	if cfg.txnID != nil && cfg.onRevokedCommit != nil {
+		return errors.New("invalid OnPartitionsRevokedCommit specified with a transactional ID: transactional group consumers commit offsets when ending transactions")
+	}
 
 	return nil
 }
@@ -407,6 +528,7 @@ func defaultCfg() cfg {
 		softwareVersion: softwareVersion(),
 
 		logger: new(nopLogger),
+		clock:  realClock{},
 
 		seedBrokers: []string{"127.0.0.1"},
 		maxVersions: kversion.Stable(),
@@ -488,6 +610,11 @@ func defaultCfg() cfg {
 		heartbeatInterval: 3000 * time.Millisecond,
 
 		autocommitInterval: 5 * time.Second,
+		commitQueueDepth:   16,
+
+		joinMetadataMaxBytes: 1 << 20, // Kafka message.max.bytes default is 1048588
+
+		slowCallbackFraction: 0.5,
 	}
 }
 
@@ -610,6 +737,29 @@ func DialTLSConfig(c *tls.Config) Opt {
 	})
 }
 
+// InterceptRequests sets a function that is called before every request
+// issued through Client.Request (and thus any kmsg request's RequestWith).
+// If the function returns a non-nil response or error, that is returned as
+// the request's result and nothing is sent to Kafka. If the function returns
+// a nil response and nil error, the request is issued as normal.
+//
+// This is meant for testing: group management issues its JoinGroup,
+// SyncGroup, Heartbeat, LeaveGroup, OffsetFetch, and OffsetCommit requests
+// through Client.Request, meaning tests can stub these requests to drive a
+// group consumer through rebalances without a real broker. Blocking within
+// the function until a test allows the request to continue can be used to
+// step the group's lifecycle, and hooks such as HookGroupSessionBegin and
+// HookGroupManageError can be used to observe it. Metadata requests and
+// produce and fetch requests are issued directly to brokers and are not
+// intercepted.
+//
+// The returned response must be the response type for the request (e.g., a
+// *kmsg.JoinGroupResponse for a *kmsg.JoinGroupRequest). The request must not
+// be modified.
+func InterceptRequests(fn func(ctx context.Context, req kmsg.Request) (kmsg.Response, error)) Opt {
+	return clientOpt{func(cfg *cfg) { cfg.interceptRequest = fn }}
+}
+
 // SeedBrokers sets the seed brokers for the client to use, overriding the
 // default 127.0.0.1:9092.
 //
@@ -843,6 +993,13 @@ func DisableIdempotentWrite() ProducerOpt {
 // example, zstd compression was introduced in Kafka 2.1.0, so the preference
 // can be first zstd, fallback snappy, fallback none.
 //
+// Support is negotiated per broker: every produce request is compressed with
+// the first preferred codec that the request's broker supports, as determined
+// by the produce request version negotiated with ApiVersions. In a cluster with
+// a mix of old and new brokers, a preference of [zstd, snappy] uses zstd when
+// producing to new brokers and snappy when producing to old brokers. If no
+// codec in the preference is supported, batches are not compressed.
+//
 // The default preference is [snappy, none], which should be fine for all old
 // consumers since snappy compression has existed since Kafka 0.8.0.  To use
 // zstd, your brokers must be at least 2.1.0 and all consumers must be upgraded
@@ -851,6 +1008,34 @@ func ProducerBatchCompression(preference ...CompressionCodec) ProducerOpt {
 	return producerOpt{func(cfg *cfg) { cfg.compression = preference }}
 }
 
+// ProducerBatchCompressionCache opts into caching up to n compressed batch
+// payloads, such that compressing a payload identical to a recently compressed
+// payload reuses the prior compressed bytes rather than compressing again.
+//
+// This is useful for fan-out workloads that produce the same records to many
+// partitions, where identical batches are otherwise compressed once per
+// partition. Payloads are hashed and then compared in full before reusing a
+// cached entry, so each entry costs memory for both the uncompressed and
+// compressed payload; the least recently used entry is evicted once the cache
+// is full. The cache is keyed by the codec actually used, which can vary with
+// produce request version for different brokers. By default, nothing is
+// cached.
+func ProducerBatchCompressionCache(n int) ProducerOpt {
+	return producerOpt{func(cfg *cfg) { cfg.compressionCache = n }}
+}
+
+// MinCompressionSize sets the minimum uncompressed size, in bytes, that a
+// batch payload must be before it is compressed, overriding the default of 0.
+//
+// Compressing tiny batches (a record or two with small values) wastes CPU and
+// frequently produces output larger than the input. Payloads below this
+// threshold are written uncompressed with codec 0, while larger payloads are
+// still compressed with the ProducerBatchCompression preference. By default,
+// every batch is compressed.
+func MinCompressionSize(n int) ProducerOpt {
+	return producerOpt{func(cfg *cfg) { cfg.minCompressionSize = n }}
+}
+
 // ProducerBatchMaxBytes upper bounds the size of a record batch, overriding
 // the default 1MB.
 //
@@ -1233,6 +1418,54 @@ func DisableFetchSessions() ConsumerOpt {
 	return consumerOpt{func(cfg *cfg) { cfg.disableFetchSessions = true }}
 }
 
+// SnappyFramedFallback opts in to decoding snappy compressed batches in the
+// snappy framing (stream) format if they fail to decode as a raw snappy
+// block.
+//
+// Kafka's snappy compressed batches are either xerial framed (as written by
+// the Java client) or are raw snappy blocks (as written by most other
+// clients). The client always tries xerial framing first if the data begins
+// with the xerial header, and otherwise decodes a raw snappy block. Some
+// non-Java producers write the snappy framing format instead; this option
+// allows consuming those batches. This is not the default so that corrupt
+// data is not misinterpreted.
+func SnappyFramedFallback() ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.snappyFramedFallback = true }}
+}
+
+// ConsumeContext sets a context that, once canceled, stops the client from
+// consuming without leaving the group or closing the client.
+//
+// By default, the only way to stop consuming is to leave the group (or close
+// the client), which conflates "stop processing" with "give up partitions".
+// After this context is canceled, the client stops issuing fetch requests,
+// and PollFetches and PollRecords return immediately with no records
+// (anything already buffered is kept buffered). For group consumers, the
+// client remains in the group and continues heartbeating, meaning you can,
+// for example, finish a long flush and commit before leaving the group.
+//
+// Canceling the context is permanent; to consume again, create a new client.
+func ConsumeContext(ctx context.Context) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.consumeCtx = ctx }}
+}
+
+// ValidateDecompressedBatches sets a function to be called with the raw
+// records of every fetched record batch, after decompressing the batch but
+// before parsing any records. If the function returns an error, the batch is
+// skipped (none of its records are returned and consuming continues after the
+// batch) and a warning is logged with the error.
+//
+// This can act as a cheap data quality gate, for example to drop batches from
+// producers known to write a bad format without paying to parse every record.
+// The decompressed bytes are the concatenated records of the batch, as
+// defined in the Kafka record batch format, and must not be retained or
+// modified. Batches that are not compressed are passed as is. This is only
+// called for record batches (magic 2, Kafka 0.11+), not for the older message
+// set formats.
+func ValidateDecompressedBatches(fn func(topic string, partition int32, decompressed []byte) error) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.validateBatch = fn }}
+}
+
 //////////////////////////////////
 // CONSUMER GROUP CONFIGURATION //
 //////////////////////////////////
@@ -1248,6 +1481,13 @@ func DisableFetchSessions() ConsumerOpt {
 // client shuts down, you should issue one final synchronous commit before
 // leaving the group (because you will not be polling again, and you are not
 // waiting for an autocommit).
+//
+// A client consumes in at most one group: all group state (the member ID,
+// generation, assignment, and uncommitted offsets) and every group option
+// applies to that single group, and polled records are not tagged with a
+// group. To consume many groups, use one client per group. Each client has
+// its own broker connections and metadata; to reduce load when running many
+// small groups, consider raising MetadataMinAge and MetadataMaxAge.
 func ConsumerGroup(group string) GroupOpt {
 	return groupOpt{func(cfg *cfg) { cfg.group = group }}
 }
@@ -1304,13 +1544,26 @@ func RebalanceTimeout(timeout time.Duration) GroupOpt {
 //
 // Kafka uses heartbeats to ensure that a group member's session stays active.
 // This value can be any value lower than the session timeout, but should be no
-// higher than 1/3rd the session timeout.
+// higher than 1/3rd the session timeout. The client validates the latter unless
+// SkipGroupTimeoutValidation is used.
 //
 // This corresponds to Kafka's heartbeat.interval.ms.
 func HeartbeatInterval(interval time.Duration) GroupOpt {
 	return groupOpt{func(cfg *cfg) { cfg.heartbeatInterval = interval }}
 }
 
+// SkipGroupTimeoutValidation opts out of validating that the heartbeat
+// interval is less than a third of the session timeout and that the rebalance
+// timeout is at least the session timeout. These are Kafka's recommendations,
+// and by default, the client fails construction if they are not met.
+//
+// Even with this option, the heartbeat interval must be less than the session
+// timeout: otherwise, the member would be evicted between heartbeats. This
+// option is only for advanced users that know what they are doing.
+func SkipGroupTimeoutValidation() GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.skipGroupTimeoutCheck = true }}
+}
+
 // RequireStableFetchOffsets sets the group consumer to require "stable" fetch
 // offsets before consuming from the group. Proposed in KIP-447 and introduced
 // in Kafka 2.5.0, stable offsets are important when consuming from partitions
@@ -1324,11 +1577,209 @@ func HeartbeatInterval(interval time.Duration) GroupOpt {
 // Because this can block consumption, it is strongly recommended to set
 // transactional timeouts to a small value (10s) rather than the default 60s.
 // Lowering the transactional timeout will reduce the chance that consumers are
-// entirely blocked.
+// entirely blocked. This can be toggled at runtime with SetRequireStable.
 func RequireStableFetchOffsets() GroupOpt {
 	return groupOpt{func(cfg *cfg) { cfg.requireStable = true }}
 }
 
+// MaxAssignedPartitions sets the maximum number of partitions this group member
+// will accept from a group assignment, overriding the default of no limit (0).
+//
+// If the group leader assigns this member more partitions than allowed, the
+// member logs a warning and drops the extra partitions, keeping the first
+// partitions in topic and partition order. Dropped partitions are not consumed
+// by anybody until the group rebalances; this option is a safety valve for
+// memory constrained members in heterogeneous fleets, and the group should be
+// scaled out such that the limit is not hit in normal operation.
+func MaxAssignedPartitions(n int) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.maxAssignedPartitions = n }}
+}
+
+// RebalanceRetryBackoffFn sets the backoff strategy for how long to backoff
+// before rejoining the group after a group session errors, overriding the
+// default of using the client's RetryBackoffFn.
+//
+// The input to the function is the number of consecutive group errors.
+func RebalanceRetryBackoffFn(backoff func(int) time.Duration) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.rebalanceBackoff = backoff }}
+}
+
+// OffsetFetchRetryBackoffFn sets the backoff strategy for how long to backoff
+// before retrying fetching offsets when a fetch fails with
+// UNSTABLE_OFFSET_COMMIT (i.e., a transaction is pending commit for a
+// partition), overriding the default of 1s.
+//
+// The input to the function is the number of consecutive fetch retries.
+func OffsetFetchRetryBackoffFn(backoff func(int) time.Duration) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.offsetFetchBackoff = backoff }}
+}
+
+// SlowCallbackWarnFraction sets the fraction of the session timeout that an
+// OnPartitionsAssigned, OnPartitionsRevoked, or OnPartitionsLost callback can
+// take before the client logs a warning, overriding the default of 0.5. A
+// fraction of 0 disables warning.
+//
+// Slow callbacks are a common cause of members being kicked from the group
+// and of rebalance loops. See HookGroupCallbackDuration to measure every
+// callback.
+func SlowCallbackWarnFraction(fraction float64) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.slowCallbackFraction = fraction }}
+}
+
+// BackgroundRevoke sets eager group consumers to run OnPartitionsRevoked in
+// the background while rejoining the group, overriding the default of waiting
+// for OnPartitionsRevoked to return before rejoining.
+//
+// With eager balancers, every rebalance revokes every partition, and the
+// group cannot finish rebalancing until every member has rejoined. If
+// OnPartitionsRevoked is slow (e.g., it flushes a lot of work), the slow
+// revoke adds directly to the group's rebalance downtime. With this option,
+// the client rejoins immediately; before fetching offsets for the next
+// assignment (and before calling OnPartitionsAssigned), the client waits for
+// the background revoke to finish.
+//
+// This trades strictness for lower rebalance downtime: the revoked partitions
+// may be assigned to and consumed by other members before the background
+// revoke commits, meaning records may be processed twice, and a late commit
+// may overwrite a newer commit from the new owner. This has no effect for
+// cooperative consumers nor when leaving the group.
+func BackgroundRevoke() GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.backgroundRevoke = true }}
+}
+
+// LeaveOnStuckRebalance sets the client to leave the group and rejoin with a
+// new member ID if joining and syncing the group does not complete within
+// multiple times the rebalance timeout. By default, the client waits however
+// long the group coordinator takes.
+//
+// If one member of a group never completes its rejoin, the whole group can be
+// stuck in PREPARING_REBALANCE, and the coordinator may keep waiting past the
+// rebalance timeout. Leaving and rejoining with a fresh member ID can break
+// the deadlock from a healthy member. When this happens, partitions are lost
+// (OnPartitionsLost is called), HookGroupRebalanceStuck is called, and the
+// client then rejoins after the normal rebalance backoff. Static members
+// (InstanceID) also leave with their instance ID. A multiple less than or
+// equal to 1 is not recommended, since a normal rebalance can take up to the
+// rebalance timeout.
+func LeaveOnStuckRebalance(multiple float64) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.stuckRebalanceMultiple = multiple }}
+}
+
+// ConsumeLatestOnFirstJoin sets the client to ignore committed offsets the
+// first time offsets are fetched after the client is created, instead
+// consuming every assigned partition from the end. All later offset fetches
+// (i.e., after rebalances within the same process) use committed offsets as
+// normal.
+//
+// This is useful for consumers that only care about new data and want to skip
+// any backlog on startup, while still resuming where they left off across
+// rebalances. This differs from ConsumeResetOffset, which only applies to
+// partitions that have no committed offset.
+func ConsumeLatestOnFirstJoin() GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.latestOnFirstJoin = true }}
+}
+
+// FailOnUnrequestedTopicAssignment sets the group consumer to treat being
+// assigned a topic that it is not consuming as a fatal error, rather than
+// logging a warning and skipping the topic.
+//
+// Being assigned a topic that was not in the member's subscription indicates a
+// bug in the group's balancer, and skipping the topic means nothing in the
+// group consumes it. If this option is set, the assignment is considered lost
+// (OnPartitionsLost is called), the error is passed to HookGroupManageError as
+// an *ErrUnrequestedTopicAssigned, and the client stops managing the group.
+// The client does not rejoin; you must close the client and fix the balancer.
+//
+// Topics that were consumed via regex and that the client has since decided
+// it no longer wants are not affected by this option.
+func FailOnUnrequestedTopicAssignment() GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.failUnrequestedTopics = true }}
+}
+
+// RevokeTimeout sets a timeout on the context passed to OnPartitionsRevoked,
+// overriding the default of no timeout (the context is only canceled when the
+// client is closed).
+//
+// The client continues heartbeating while waiting for a revoke to finish, but
+// the group only waits up to the rebalance timeout for this member to rejoin.
+// If a commit in OnPartitionsRevoked hangs, the member is kicked from the
+// group and the revoke blocks the group session from ending. Bounding the
+// revoke context allows the default blocking commit in OnPartitionsRevoked
+// (and any user commit using the input context) to be canceled, at which point
+// the session ends and the client rejoins. A good value is somewhat below the
+// rebalance timeout. A zero timeout means no timeout.
+func RevokeTimeout(timeout time.Duration) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.revokeTimeout = timeout }}
+}
+
+// OnAssignedOnlyChanged sets the client to only call OnPartitionsAssigned if
+// new partitions were assigned since the prior group session, overriding the
+// default of always calling OnPartitionsAssigned at the start of every
+// session, even if nothing new is assigned.
+//
+// This is useful for cooperative consumers, where a rebalance that does not
+// add partitions to this member would otherwise trigger a no-op
+// OnPartitionsAssigned. Eager balancers revoke everything before every
+// rebalance, meaning everything is always new and OnPartitionsAssigned is
+// always called.
+func OnAssignedOnlyChanged() GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.skipUnchangedAssigned = true }}
+}
+
+// FetchAssignedSubset sets the client to only fetch the assigned partitions
+// that are also in the input allowlist. This is meant for debugging: the
+// member joins the group and participates in balancing normally, and all
+// assigned partitions remain owned by this member (as reported to the group
+// when rejoining), but only the allowed subset of partitions is fetched.
+//
+// Partitions that are assigned but not fetched never have their offsets
+// fetched, and thus are never committed.
+func FetchAssignedSubset(partitions map[string][]int32) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.fetchAssignedSubset = partitions }}
+}
+
+// AdjustJoinGroupRequestFn sets a function to be called with every
+// JoinGroupRequest just before the request is issued, allowing the request to
+// be modified.
+//
+// This is an escape hatch for interop and experimentation, such as adjusting
+// the rebalance timeout or adding protocols. The group, member ID, and
+// generation related fields should not be modified; doing so will break group
+// management.
+func AdjustJoinGroupRequestFn(fn func(*kmsg.JoinGroupRequest)) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.adjustJoinGroupRequest = fn }}
+}
+
+// JoinGroupMetadataMaxBytes sets the size past which the protocol metadata in
+// a JoinGroupRequest is considered dangerously large, overriding the default
+// of 1MiB. Setting this to 0 disables the size check.
+//
+// The group coordinator persists every member's metadata in one record in
+// __consumer_offsets, meaning large metadata (from consuming thousands of
+// topics, or from cooperative balancers reporting thousands of owned
+// partitions) can cause the broker to fail the group with an unhelpful
+// error. If the total metadata across all protocols exceeds this size, the
+// client logs a warning with the size of each protocol's metadata before
+// joining. See TrimJoinGroupOwnedPartitions to additionally shrink the
+// metadata.
+func JoinGroupMetadataMaxBytes(n int32) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.joinMetadataMaxBytes = n }}
+}
+
+// TrimJoinGroupOwnedPartitions opts into dropping currently owned partitions
+// from the join metadata if the metadata exceeds JoinGroupMetadataMaxBytes.
+//
+// Owned partitions are what allow sticky balancers to keep partitions on the
+// same member, and what allow cooperative balancers to revoke partitions
+// before they move. Without them, the leader balances as if this member owns
+// nothing: the balance is less sticky, and with cooperative balancing, a
+// partition moving away from this member may briefly be consumed by both this
+// member and its new owner until this member revokes it. This is a last
+// resort to keep a group with very large metadata functional.
+func TrimJoinGroupOwnedPartitions() GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.joinMetadataTrimOwned = true }}
+}
+
 // AdjustFetchOffsetsFn sets the function to be called when a group is joined
 // after offsets are fetched for those partitions so that a user can adjust them
 // before consumption begins.
@@ -1345,6 +1796,88 @@ func AdjustFetchOffsetsFn(adjustOffsetsBeforeAssign func(context.Context, map[st
 	return groupOpt{func(cfg *cfg) { cfg.adjustOffsetsBeforeAssign = adjustOffsetsBeforeAssign }}
 }
 
+// ExternalOffsetsFn sets the function to be called when a group is joined to
+// load the offsets to consume newly assigned partitions from, overriding the
+// default of issuing an OffsetFetch to Kafka. This is for users that store
+// offsets outside of Kafka and only use the group for partition assignment.
+//
+// The function is passed a context that is canceled if the current group
+// session finishes, as well as the newly assigned partitions. Any assigned
+// partition that the function does not return an offset for (or returns a
+// negative offset for) is consumed from the ConsumeResetOffset. Returned
+// offsets are treated as committed: they are what CommittedOffsets returns,
+// and autocommitting does not commit them. You likely want to disable
+// autocommitting when using this option. If the function returns an error,
+// the group session ends as if fetching offsets failed, and the client
+// rejoins after backing off.
+//
+// AdjustFetchOffsetsFn, if set, is still called with the offsets returned
+// from this function. The same rebalance timeout advice for
+// AdjustFetchOffsetsFn applies to this function.
+func ExternalOffsetsFn(fn func(ctx context.Context, added map[string][]int32) (map[string]map[int32]EpochOffset, error)) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.externalOffsets = fn }}
+}
+
+// RejoinOnMetadataChangeFn sets the function to be called on metadata updates
+// to decide whether the group member should rejoin the group, overriding the
+// default of rejoining if new topics are found to consume or if the member is
+// the leader and notices more partitions in an existing topic.
+//
+// The function is called with the topics (and the number of partitions in each
+// topic) that the member is currently using, and the topics and partitions the
+// member would use after the metadata update. If nothing changed, the two maps
+// are equal. The function is free to consult anything external to decide
+// whether a rejoin is warranted, which allows custom balancers whose metadata
+// depends on more than partition counts to avoid unnecessary rebalances or to
+// force rebalances. Returning true triggers a rejoin.
+//
+// The function is called on every metadata update once the group is being
+// managed. Note that if new topics are found and the function returns false,
+// the new topics are not consumed until the next time the member rejoins.
+//
+// This function is called while the client's consumer is locked; it must be
+// fast and must not call back into the client.
+//
+// NewPartitionsRejoinDelay has no effect if this function is set.
+func RejoinOnMetadataChangeFn(fn func(current, proposed map[string]int) bool) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.rejoinOnMetadataChange = fn }}
+}
+
+// NewPartitionsRejoinDelay sets how long the group leader waits to rejoin the
+// group after noticing new partitions in topics the group is consuming,
+// overriding the default of rejoining immediately.
+//
+// For topics whose partition counts change frequently, rejoining immediately
+// rebalances the whole group on every change. With a positive delay, all new
+// partitions noticed within the delay are coalesced into one rejoin, and if
+// the group rebalances for any other reason during the delay, the delayed
+// rejoin is skipped (the rebalance already picks up the new partitions). A
+// negative delay disables leader-driven rejoins entirely, meaning new
+// partitions are only consumed after the next rebalance for some other
+// reason.
+//
+// This does not affect rejoins for new topics being consumed.
+func NewPartitionsRejoinDelay(delay time.Duration) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.newPartitionsRejoinDelay = delay }}
+}
+
+// SubscriptionRejoinDebounce sets a window to coalesce rejoins that are
+// triggered by subscription changes, overriding the default of rejoining as
+// soon as a metadata update notices new topics to consume.
+//
+// When regex consuming in an environment where many topics are created at
+// once, the topics may be discovered across several metadata updates, and
+// each update rejoins and rebalances the group. With a positive window, a
+// rejoin for new topics waits until no further new topics have been noticed
+// for the window, and then the group rejoins once. A continuous stream of
+// topic creations therefore delays the rejoin until the stream settles.
+//
+// This also applies to rejoins from RejoinOnMetadataChangeFn, but does not
+// affect NewPartitionsRejoinDelay nor any other reason to rejoin.
+func SubscriptionRejoinDebounce(window time.Duration) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.subscriptionRejoinWindow = window }}
+}
+
 // OnPartitionsAssigned sets the function to be called when a group is joined
 // after partitions are assigned before fetches for those partitions begin.
 //
@@ -1356,7 +1889,8 @@ func AdjustFetchOffsetsFn(adjustOffsetsBeforeAssign func(context.Context, map[st
 // only canceled if the client is closed.
 //
 // This function is not called concurrent with any other On callback, and this
-// function is given a new map that the user is free to modify.
+// function is given a new map that the user is free to modify. The partitions
+// for each topic in the map are sorted.
 func OnPartitionsAssigned(onAssigned func(context.Context, *Client, map[string][]int32)) GroupOpt {
 	return groupOpt{func(cfg *cfg) { cfg.onAssigned, cfg.setAssigned = onAssigned, true }}
 }
@@ -1377,30 +1911,68 @@ func OnPartitionsAssigned(onAssigned func(context.Context, *Client, map[string][
 // completes.
 //
 // The OnPartitionsRevoked function is passed the client's context, which is
-// only canceled if the client is closed. OnPartitionsRevoked function is
+// only canceled if the client is closed (or, if RevokeTimeout is set, when
+// the timeout elapses). OnPartitionsRevoked function is
 // called at the end of a group session even if there are no partitions being
 // revoked. If you are committing offsets manually (have disabled
 // autocommitting), it is highly recommended to do a proper blocking commit in
 // OnPartitionsRevoked.
 //
+// Revoked partitions are still owned by this member until the function
+// returns, meaning it is safe (and expected) to commit in this function. This
+// function is called when a rebalance begins and when leaving the group (i.e.,
+// when closing the client or calling LeaveGroup). When leaving, this is only
+// called if the member still owns partitions: if a rebalance already revoked
+// everything, this is not called again with nothing to revoke. All buffered
+// fetches for the revoked partitions are dropped before this function is
+// called. Outside of a GroupTransactSession, this function is never called in
+// place of OnPartitionsLost; see that option for more details.
+//
 // This function is not called concurrent with any other On callback, and this
-// function is given a new map that the user is free to modify.
+// function is given a new map that the user is free to modify. The partitions
+// for each topic in the map are sorted.
 func OnPartitionsRevoked(onRevoked func(context.Context, *Client, map[string][]int32)) GroupOpt {
 	return groupOpt{func(cfg *cfg) { cfg.onRevoked, cfg.setRevoked = onRevoked, true }}
 }
 
+// OnPartitionsRevokedCommit sets the function to be called when partitions
+// are revoked, in place of OnPartitionsRevoked, with the client performing a
+// blocking commit of the returned offsets before the revoke proceeds.
+//
+// This encodes the recommended pattern of committing exactly what has been
+// processed in OnPartitionsRevoked: the function returns the offsets to
+// commit (which can be empty to commit nothing), and the client issues the
+// commit just as CommitOffsetsSync would, with the same context that
+// OnPartitionsRevoked is passed, calling the AutoCommitCallback (or the
+// default callback, which logs) once the commit is done. The revoke does not
+// continue until the commit completes.
+//
+// All documentation on OnPartitionsRevoked applies to this function. It is
+// invalid to use both this option and OnPartitionsRevoked, or to use this
+// option with a transactional ID.
+func OnPartitionsRevokedCommit(onRevoked func(context.Context, *Client, map[string][]int32) map[string]map[int32]EpochOffset) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.onRevokedCommit = onRevoked }}
+}
+
 // OnPartitionsLost sets the function to be called on "fatal" group errors,
 // such as IllegalGeneration, UnknownMemberID, and authentication failures.
 // This function differs from OnPartitionsRevoked in that it is unlikely that
 // commits will succeed when partitions are outright lost, whereas commits
 // likely will succeed when revoking partitions.
 //
-// If this is not set, you will not know when a group error occurs that
-// forcefully loses all partitions. If you wish to use the same callback for
-// lost and revoked, you can use OnPartitionsLostAsRevoked as a shortcut.
+// Lost partitions are already gone: another member may own them by the time
+// this function is called. You should not commit in this function, and you
+// should drop any state you have for the lost partitions.
+//
+// If this is not set, nothing is called when partitions are lost and you will
+// not know when a group error occurs that forcefully loses all partitions.
+// The client never falls back to OnPartitionsRevoked, except within a
+// GroupTransactSession (see NewGroupTransactSession). If you wish to use the
+// same callback for lost and revoked, you must set both options.
 //
 // This function is not called concurrent with any other On callback, and this
-// function is given a new map that the user is free to modify.
+// function is given a new map that the user is free to modify. The partitions
+// for each topic in the map are sorted.
 func OnPartitionsLost(onLost func(context.Context, *Client, map[string][]int32)) GroupOpt {
 	return groupOpt{func(cfg *cfg) { cfg.onLost, cfg.setLost = onLost, true }}
 }
@@ -1444,6 +2016,74 @@ func AutoCommitInterval(interval time.Duration) GroupOpt {
 	return groupOpt{func(cfg *cfg) { cfg.autocommitInterval = interval }}
 }
 
+// AutoCommitTopicIntervals sets per-topic autocommit intervals, overriding
+// AutoCommitInterval for the given topics. Topics without an override are
+// committed on the AutoCommitInterval cadence.
+//
+// Autocommitting ticks at the smallest of all intervals, and each tick commits
+// only the topics whose interval has elapsed since they were last successfully
+// committed. A topic whose commit fails (or is canceled by a later commit) is
+// included again in the next commit.
+func AutoCommitTopicIntervals(intervals map[string]time.Duration) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.autocommitTopics = intervals }}
+}
+
+// AutoCommitOnFirstPoll sets the client to autocommit as soon as polled
+// offsets first become available to commit, rather than waiting a full
+// AutoCommitInterval after the client is initialized. After the first commit,
+// autocommitting settles into the normal interval cadence.
+//
+// By default, autocommitting only commits what was *previously* polled, so the
+// early commit happens on the second poll. With GreedyAutoCommit, the early
+// commit happens on the first non-empty poll, and with AutoCommitMarks, the
+// early commit happens on the first MarkCommitRecords.
+//
+// This option reduces reprocessing if your application crashes shortly after
+// starting, without lowering the steady state autocommit interval.
+func AutoCommitOnFirstPoll() GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.autocommitFirstPoll = true }}
+}
+
+// AutoCommitSkipUnchanged opts into skipping partitions in autocommits if the
+// offset to commit is the offset that is already committed. If no partition has
+// progressed, the autocommit is skipped entirely.
+//
+// Autocommits always skip partitions that have had nothing polled since the
+// prior commit, so an idle consumer does not commit. This option covers the
+// window after records are polled but before the next poll: the offset to
+// commit only advances on the next poll, so by default, a consumer that is
+// stalled processing a polled batch re-commits its already committed offset
+// every interval. With this option, those commits are skipped until the
+// consumer polls again. Note that for brokers before Kafka 2.1.0, committed
+// offsets expire based on when they were last committed (see
+// offsets.retention.minutes), and skipping unchanged commits can lead to
+// offsets for stalled partitions expiring.
+func AutoCommitSkipUnchanged() GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.autocommitSkipSame = true }}
+}
+
+// CommitOffsetTracker sets a function that is consulted for the offset to
+// commit for every partition, instead of the head offset of what has been
+// polled. This applies to autocommitting, the default OnPartitionsRevoked
+// commit, UncommittedOffsets, CommitUncommittedOffsets, and
+// CommitUntilDrained.
+//
+// This encodes the common pattern of processing records out of order (e.g.,
+// concurrently) while only committing the highest contiguous prefix of
+// processed offsets per partition: the function should return the offset
+// just past the last record in the contiguous processed prefix (i.e., the
+// offset to resume consuming at), the leader epoch of that last record, and
+// true. If the function returns false, nothing is committed for the
+// partition. The function is only called for partitions that have been
+// polled or that have a committed offset.
+//
+// The function is called while the client holds an internal group lock and
+// must not call back into the client. This option cannot be used with
+// AutoCommitMarks.
+func CommitOffsetTracker(fn func(topic string, partition int32) (offset int64, epoch int32, ok bool)) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.commitTracker = fn }}
+}
+
 // AutoCommitMarks switches the autocommitting behavior to only commit "marked"
 // records, which can be done with the MarkCommitRecords method.
 //
@@ -1480,20 +2120,150 @@ func AutoCommitMarks() GroupOpt {
 // issues a leave group request on behalf of this instance ID (see kcl), or you
 // can manually use the kmsg package with a proper LeaveGroupRequest.
 //
+// Two members using the same instance ID fence each other. If a client is
+// created with the same group and instance ID as an existing client in the
+// same process (that has not yet left the group or been closed), the new
+// client logs a warning.
+//
 // NOTE: Leaving a group with an instance ID is only supported in Kafka 2.4.0+.
 func InstanceID(id string) GroupOpt {
 	return groupOpt{func(cfg *cfg) { cfg.instanceID = &id }}
 }
 
+// InitialMemberID sets the member ID to use when first joining the group,
+// overriding the default of joining with an empty member ID and having the
+// broker assign one. This is meant for brokers that do not support static
+// membership (see InstanceID), for some continuity across restarts.
+//
+// If the member ID is still known to the group (for example, a client
+// restarts before its prior session times out), the client resumes that
+// membership. If the broker does not know the member ID, it replies with
+// UNKNOWN_MEMBER_ID and the client rejoins without a member ID, falling back
+// to a broker assigned ID. Either way, the ID is also useful for correlating
+// logs across restarts. Two clients must never use the same member ID.
+func InitialMemberID(id string) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.memberID = id }}
+}
+
 // GroupProtocol sets the group's join protocol, overriding the default value
 // "consumer". The only reason to override this is if you are implementing
 // custom join and sync group logic.
+//
+// The protocol type is sent as is in every JoinGroup request, and the
+// balancers passed to Balancers are fully responsible for encoding join
+// metadata, balancing, and decoding assignments. Thus, to use the client's
+// join, sync, and heartbeat machinery for a non-consumer workload (e.g.,
+// Kafka Connect's "connect" protocol), set this option and provide custom
+// GroupBalancers whose MemberBalancer decodes each member's raw
+// ProtocolMetadata. The assignment returned from ParseSyncAssignment is
+// still treated as the topics and partitions to consume.
 func GroupProtocol(protocol string) GroupOpt {
 	return groupOpt{func(cfg *cfg) { cfg.protocol = protocol }}
 }
 
+// OmitCommitMetadata sets the client to leave the metadata field of every
+// committed partition null, overriding the default of using the group member
+// ID as the metadata. This applies to both normal and transactional commits.
+//
+// Commit metadata is returned to anything that fetches the group's committed
+// offsets; this option is useful if tooling parses commit metadata and does
+// not expect the client's member ID.
+func OmitCommitMetadata() GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.omitCommitMetadata = true }}
+}
+
+// CommitMetadata sets the metadata used for every committed partition,
+// overriding the default of using the group member ID as the metadata. This
+// applies to both normal and transactional commits.
+//
+// Commit metadata is shown in the metadata column of standard group tooling
+// (e.g., kafka-consumer-groups.sh --describe); setting this to something that
+// identifies the process, such as the hostname and pid, makes it easy to see
+// which process last committed a partition. Brokers reject commits with
+// metadata larger than offset.metadata.max.bytes, which defaults to 4096.
+func CommitMetadata(metadata string) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.commitMetadata = &metadata }}
+}
+
+// CommitMaxPartitionsPerRequest splits every offset commit into requests of
+// at most n partitions each, overriding the default of committing everything
+// in one request. A value of 0 disables splitting.
+//
+// A member that owns thousands of partitions can build an OffsetCommit
+// request that is larger than the broker allows. With this option, the
+// requests for a commit are issued sequentially; HookGroupOffsetCommit is
+// called for each request, and the commit's onDone is called once after all
+// requests, with the merged response. If one request fails, later requests
+// are not issued, and onDone is called with the error as well as the merged
+// response of the earlier requests that did commit (LastCommitErrors also
+// reports which partitions failed). If the first request fails, the response
+// is nil. This does not affect transactional commits.
+func CommitMaxPartitionsPerRequest(n int) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.commitMaxPartitions = n }}
+}
+
+// OffsetsClient directs the group's offset commits and committed offset
+// fetches to the cluster that cl talks to, rather than the cluster the group
+// is consumed from. This separates where records are read from and where
+// offsets live, which is useful for cross cluster offset synchronization
+// (e.g., MirrorMaker style tooling).
+//
+// Group membership (joining, syncing, heartbeating) stays on the primary
+// cluster, meaning the other cluster does not know of this member: commits
+// to cl are issued with a generation of -1 and no member or instance ID, and
+// Kafka only accepts such commits if the group has no active members in that
+// cluster. Fetching committed offsets when a session begins, SeekToCommitted,
+// and every commit (including autocommits and CommitRecordsPerPartition) go
+// to cl; transactional commits do not. The input client must not itself be
+// consuming as a group, and is not closed when this client is closed.
+func OffsetsClient(cl *Client) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.offsetsClient = cl }}
+}
+
+// RejectCommitRewinds opts into rejecting any CommitOffsets,
+// CommitOffsetsSync, or CommitOffsetsQueued call that would commit an offset
+// less than the offset this client has most recently committed (or fetched as
+// committed) for any partition. Rejected commits are not issued at all, and
+// onDone is called with an *ErrCommitRewind containing the partitions that
+// would have been rewound. All commit functions that are built on these, such
+// as CommitRecords, are also checked; CommitResetToEarliest is an intentional
+// rewind and is not.
+//
+// Rewinding commits is valid but rarely intended; this option is a safety net
+// against application bugs that commit stale offsets and cause reprocessing.
+// Autocommits and the default revoke commit are never rewinds and are not
+// checked.
+func RejectCommitRewinds() GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.rejectCommitRewinds = true }}
+}
+
+// CommitQueueDepth sets how many commits can be queued with
+// CommitOffsetsQueued before further queued commits block, overriding the
+// default of 16.
+func CommitQueueDepth(depth int) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.commitQueueDepth = depth }}
+}
+
+// AutoCommitPartitionErrorsFn sets a function to be called if an autocommit
+// response has any partition errors, in addition to the autocommit callback.
+// The function is passed all partition errors in the response. If the function
+// returns true, the client rejoins the group.
+//
+// This allows reacting to persistent autocommit failures (for example,
+// ILLEGAL_GENERATION) without replacing the autocommit callback. To treat an
+// error as fatal, the function can record the error and close the client or
+// leave the group from a separate goroutine; this function must not block.
+func AutoCommitPartitionErrorsFn(fn func(*Client, map[string]map[int32]error) bool) GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.autocommitPartitionErrs = fn }}
+}
+
 // AutoCommitCallback sets the callback to use if autocommitting is enabled.
 // This overrides the default callback that logs errors and continues.
+//
+// This callback is also called with the result of the commit issued on behalf
+// of OnPartitionsRevokedCommit, meaning it is valid to use this option with
+// DisableAutoCommit to observe that commit. It is invalid to use this option
+// with a transactional ID.
 func AutoCommitCallback(fn func(*Client, *kmsg.OffsetCommitRequest, *kmsg.OffsetCommitResponse, error)) GroupOpt {
 	return groupOpt{func(cfg *cfg) { cfg.commitCallback, cfg.setCommitCallback = fn, true }}
 }
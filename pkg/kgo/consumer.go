@@ -2,6 +2,7 @@ package kgo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -114,6 +115,21 @@ type consumer struct {
 	d  *directConsumer // if non-nil, we are consuming partitions directly
 	g  *groupConsumer  // if non-nil, we are consuming as a group member
 
+	// lastDiscarded is the number of buffered records per partition that
+	// the last invalidating assignPartitions discarded. This is set and
+	// read under mu.
+	lastDiscarded map[string]map[int32]int
+
+	// lastInvalidated is the offset each partition was at (or was loading
+	// from) when the last assignInvalidateMatching unassigned it, which
+	// allows assigning the partition again later. This is set and read
+	// under mu.
+	lastInvalidated map[string]map[int32]Offset
+
+	// pausedOffsets are the offsets of partitions unassigned by
+	// PauseTopics, which ResumeTopics assigns again. This is under mu.
+	pausedOffsets map[string]map[int32]Offset
+
 	// On metadata update, if the consumer is set (direct or group), the
 	// client begins a goroutine that updates the consumer kind's
 	// assignments.
@@ -229,17 +245,42 @@ func (c *consumer) addFakeReadyForDraining(topic string, partition int32, err er
 // has no topic, a partition of 0, and a partition error of ErrClientClosed.
 // This can be used to detect if the client is closing and to break out of a
 // poll loop.
+//
+// This returns every buffered record; use PollRecords to bound the number of
+// records returned per poll.
 func (cl *Client) PollFetches(ctx context.Context) Fetches {
 	return cl.PollRecords(ctx, 0)
 }
 
+// BlockingPollFetches is like PollFetches, but if the client is consuming as
+// a group and the group is rebalancing, this first waits until the new
+// assignment is ready: the group session has begun heartbeating, offsets have
+// been fetched for all assigned partitions, and OnPartitionsAssigned has
+// returned. This allows a simple poll and process loop to avoid seeing the
+// transient empty or partial assignment while rebalancing.
+//
+// If the context quits while waiting, or the group is left, this stops
+// waiting and returns whatever PollFetches returns with the same context.
+// A rebalance can still begin after waiting and while polling; this only
+// ensures that polling does not begin in the middle of a rebalance.
+func (cl *Client) BlockingPollFetches(ctx context.Context) Fetches {
+	if g := cl.consumer.g; g != nil && ctx != nil {
+		g.waitAssigned(ctx)
+	}
+	return cl.PollFetches(ctx)
+}
+
 // PollRecords waits for records to be available, returning as soon as any
 // broker returns records in a fetch. If the context quits, this function
 // quits. If the context is nil or is already canceled, this function will
 // return immediately with any currently buffered records.
 //
 // This returns a maximum of maxPollRecords total across all fetches, or
-// returns all buffered records if maxPollRecords is <= 0.
+// returns all buffered records if maxPollRecords is <= 0. Any records past
+// the limit remain buffered and are returned in the next poll. For group
+// consumers, only the records actually returned are tracked as uncommitted,
+// meaning a commit (or autocommit) never commits past records that are still
+// buffered.
 //
 // It is important to check all partition errors in the returned fetches. If
 // any partition has a fatal error and actually had no records, fake fetch will
@@ -257,6 +298,14 @@ func (cl *Client) PollRecords(ctx context.Context, maxPollRecords int) Fetches {
 
 	c.g.undirtyUncommitted()
 
+	var consumeDone <-chan struct{}
+	if consumeCtx := cl.cfg.consumeCtx; consumeCtx != nil {
+		if consumeCtx.Err() != nil {
+			return nil
+		}
+		consumeDone = consumeCtx.Done()
+	}
+
 	var fetches Fetches
 	fill := func() {
 		// A group can grab the consumer lock then the group mu and
@@ -356,6 +405,11 @@ func (cl *Client) PollRecords(ctx context.Context, maxPollRecords int) Fetches {
 	case <-ctx.Done():
 		// The user canceled: no need to inject anything; just return.
 		exit()
+	case <-consumeDone:
+		// The user stopped consuming: we return nothing and leave
+		// everything buffered.
+		exit()
+		return nil
 	case <-done:
 	}
 
@@ -456,6 +510,135 @@ func (cl *Client) ResumeFetchPartitions(topicPartitions map[string][]int32) {
 	c.storePaused(paused)
 }
 
+// PauseTopics pauses processing the given topics while keeping them assigned,
+// which is useful for temporarily halting one topic (e.g. during a schema
+// migration) without leaving the group.
+//
+// This is a stronger form of PauseFetchTopics: the topics are paused from
+// fetching (and can be seen in PauseFetchTopics), and additionally, anything
+// already buffered for the topics is discarded and will be fetched again once
+// the topics are resumed. The paused partitions are unassigned from fetching
+// and are assigned again at the same offsets when resumed. Unassigning briefly
+// restarts every active fetch, meaning partitions of other topics may be
+// re-fetched as well, but their positions are unaffected.
+//
+// For group consumers, the topics remain owned by this member and are not
+// reassigned, but their head offsets do not advance while paused, meaning
+// autocommitting and CommitUncommittedOffsets do not commit for these topics.
+// Records that were polled before pausing become committable once the topics
+// are resumed. Explicitly committing offsets with CommitOffsets is not
+// affected.
+func (cl *Client) PauseTopics(topics ...string) {
+	if len(topics) == 0 {
+		return
+	}
+	cl.PauseFetchTopics(topics...)
+
+	c := &cl.consumer
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var tps *topicsPartitions
+	switch {
+	case c.d != nil:
+		tps = c.d.tps
+	case c.g != nil:
+		tps = c.g.tps
+		c.g.mu.Lock()
+		if c.g.pausedTopics == nil {
+			c.g.pausedTopics = make(map[string]struct{}, len(topics))
+		}
+		for _, topic := range topics {
+			c.g.pausedTopics[topic] = struct{}{}
+		}
+		c.g.mu.Unlock()
+	default:
+		return
+	}
+
+	// We unassign the paused partitions the same as a cooperative revoke
+	// does, which drops anything buffered without advancing any cursor,
+	// and save where each partition was so that resuming can assign it
+	// again. Partitions already paused are not unassigned twice.
+	topicData := tps.load()
+	invalidate := make(map[string]map[int32]Offset, len(topics))
+	for _, topic := range topics {
+		t := topicData.loadTopic(topic)
+		if t == nil {
+			continue
+		}
+		for p := range t.partitions {
+			if _, paused := c.pausedOffsets[topic][int32(p)]; paused {
+				continue
+			}
+			if invalidate[topic] == nil {
+				invalidate[topic] = make(map[int32]Offset, len(t.partitions))
+			}
+			invalidate[topic][int32(p)] = Offset{}
+		}
+	}
+	if len(invalidate) == 0 {
+		return
+	}
+	c.assignPartitions(invalidate, assignInvalidateMatching, tps, "unassigning paused topics")
+	for topic, partitions := range c.lastInvalidated {
+		if c.pausedOffsets == nil {
+			c.pausedOffsets = make(map[string]map[int32]Offset)
+		}
+		if c.pausedOffsets[topic] == nil {
+			c.pausedOffsets[topic] = make(map[int32]Offset, len(partitions))
+		}
+		for partition, offset := range partitions {
+			c.pausedOffsets[topic][partition] = offset
+		}
+	}
+}
+
+// ResumeTopics resumes processing topics previously paused with PauseTopics,
+// resuming fetching the topics and allowing their head offsets to advance
+// again. Resuming topics that are not paused is a per-topic no-op.
+func (cl *Client) ResumeTopics(topics ...string) {
+	defer cl.ResumeFetchTopics(topics...)
+
+	c := &cl.consumer
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var tps *topicsPartitions
+	switch {
+	case c.d != nil:
+		tps = c.d.tps
+	case c.g != nil:
+		tps = c.g.tps
+		c.g.mu.Lock()
+		for _, topic := range topics {
+			delete(c.g.pausedTopics, topic)
+		}
+		c.g.mu.Unlock()
+	}
+
+	// We assign paused partitions again at the offsets they were at when
+	// paused; our deferred ResumeFetchTopics then allows fetching them.
+	var resume map[string]map[int32]Offset
+	for _, topic := range topics {
+		partitions, ok := c.pausedOffsets[topic]
+		if !ok {
+			continue
+		}
+		delete(c.pausedOffsets, topic)
+		if len(partitions) == 0 || tps == nil || tps.load().loadTopic(topic) == nil {
+			continue
+		}
+		if resume == nil {
+			resume = make(map[string]map[int32]Offset, len(topics))
+		}
+		resume[topic] = partitions
+	}
+	if len(resume) > 0 {
+		c.assignPartitions(resume, assignWithoutInvalidating, tps, "resuming paused topics")
+	}
+}
+
 // SetOffsets sets any matching offsets in setOffsets to the given
 // epoch/offset. Partitions that are not specified are not set. It is invalid
 // to set topics that were not yet returned from a PollFetches: this function
@@ -473,6 +656,184 @@ func (cl *Client) SetOffsets(setOffsets map[string]map[int32]EpochOffset) {
 	cl.setOffsets(setOffsets, true)
 }
 
+// SetOffsetsLookupEpochs is like SetOffsets, but rather than requiring a leader
+// epoch for every offset, this looks up the leader epoch that each offset was
+// written in and uses that epoch when setting the offset.
+//
+// Setting an offset with an epoch of -1 disables KIP-320 log truncation
+// detection for that offset; this function is useful when seeking to offsets
+// that were obtained externally (e.g., provided by an operator) without an
+// epoch. The epoch is found by walking backwards from a partition's current
+// leader epoch with OffsetForLeaderEpoch requests until the epoch containing
+// the offset is found.
+//
+// If the brokers are too old to support leader epochs, offsets are set with
+// an epoch of -1. If any lookup fails, this returns the error and no offsets
+// are set. All of the caveats of SetOffsets apply.
+func (cl *Client) SetOffsetsLookupEpochs(ctx context.Context, setOffsets map[string]map[int32]int64) error {
+	if len(setOffsets) == 0 {
+		return nil
+	}
+	epochOffsets, err := cl.lookupOffsetEpochs(ctx, setOffsets)
+	if err != nil {
+		return err
+	}
+	cl.SetOffsets(epochOffsets)
+	return nil
+}
+
+// lookupOffsetEpochs returns the input offsets paired with the leader epoch
+// that each offset belongs to.
+//
+// An epoch E covers the offsets starting at the end offset of the epoch prior
+// to E. We begin each partition at its current leader epoch and ask for the
+// end offset of the prior epoch: if the offset we are looking up is at or past
+// that end offset, the offset is within our current candidate epoch.
+// Otherwise, the prior epoch becomes the new candidate and we ask again.
+func (cl *Client) lookupOffsetEpochs(ctx context.Context, offsets map[string]map[int32]int64) (map[string]map[int32]EpochOffset, error) {
+	// Like the OffsetForLeaderEpoch requests below, we issue metadata
+	// through Request: this lookup is user driven and bounded by ctx, so
+	// it does not need our internal retry-limited metadata path.
+	metaReq := kmsg.NewPtrMetadataRequest()
+	metaReq.AllowAutoTopicCreation = cl.cfg.allowAutoTopicCreation
+	for topic := range offsets {
+		reqTopic := kmsg.NewMetadataRequestTopic()
+		reqTopic.Topic = kmsg.StringPtr(topic)
+		metaReq.Topics = append(metaReq.Topics, reqTopic)
+	}
+	meta, err := metaReq.RequestWith(ctx, cl)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load leader epochs: %w", err)
+	}
+
+	type lookup struct {
+		offset int64
+		epoch  int32 // candidate epoch containing offset
+	}
+	lookups := make(map[string]map[int32]*lookup)
+	resolved := make(map[string]map[int32]EpochOffset)
+	resolve := func(topic string, partition int32, l *lookup) {
+		rt := resolved[topic]
+		if rt == nil {
+			rt = make(map[int32]EpochOffset)
+			resolved[topic] = rt
+		}
+		rt[partition] = EpochOffset{Epoch: l.epoch, Offset: l.offset}
+		delete(lookups[topic], partition)
+		if len(lookups[topic]) == 0 {
+			delete(lookups, topic)
+		}
+	}
+
+	for i := range meta.Topics {
+		t := &meta.Topics[i]
+		if t.Topic == nil {
+			continue
+		}
+		topic := *t.Topic
+		partitions, exists := offsets[topic]
+		if !exists {
+			continue
+		}
+		if err := kerr.ErrorForCode(t.ErrorCode); err != nil {
+			return nil, fmt.Errorf("unable to load leader epochs for topic %s: %w", topic, err)
+		}
+		for j := range t.Partitions {
+			p := &t.Partitions[j]
+			offset, exists := partitions[p.Partition]
+			if !exists {
+				continue
+			}
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("unable to load leader epoch for %s[%d]: %w", topic, p.Partition, err)
+			}
+			lt := lookups[topic]
+			if lt == nil {
+				lt = make(map[int32]*lookup)
+				lookups[topic] = lt
+			}
+			lt[p.Partition] = &lookup{offset, p.LeaderEpoch}
+		}
+	}
+	for topic, partitions := range offsets {
+		for partition := range partitions {
+			if _, exists := lookups[topic][partition]; !exists {
+				return nil, fmt.Errorf("unable to load leader epoch for %s[%d]: %w", topic, partition, kerr.UnknownTopicOrPartition)
+			}
+		}
+	}
+
+	// Any partition whose candidate is the first epoch is already
+	// resolved, as is every partition if the brokers are too old to
+	// support epochs.
+	supportsEpochs := cl.supportsOffsetForLeaderEpoch()
+	for topic, partitions := range lookups {
+		for partition, l := range partitions {
+			if !supportsEpochs || l.epoch < 0 {
+				l.epoch = -1
+				resolve(topic, partition, l)
+			} else if l.epoch == 0 {
+				resolve(topic, partition, l)
+			}
+		}
+	}
+
+	for len(lookups) > 0 {
+		req := kmsg.NewPtrOffsetForLeaderEpochRequest()
+		for topic, partitions := range lookups {
+			reqTopic := kmsg.NewOffsetForLeaderEpochRequestTopic()
+			reqTopic.Topic = topic
+			for partition, l := range partitions {
+				reqPartition := kmsg.NewOffsetForLeaderEpochRequestTopicPartition()
+				reqPartition.Partition = partition
+				reqPartition.LeaderEpoch = l.epoch - 1
+				reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
+			}
+			req.Topics = append(req.Topics, reqTopic)
+		}
+
+		resp, err := req.RequestWith(ctx, cl)
+		if err != nil {
+			return nil, fmt.Errorf("unable to look up leader epochs: %w", err)
+		}
+
+		var progressed bool
+		for i := range resp.Topics {
+			t := &resp.Topics[i]
+			for j := range t.Partitions {
+				p := &t.Partitions[j]
+				l, exists := lookups[t.Topic][p.Partition]
+				if !exists {
+					continue
+				}
+				if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+					return nil, fmt.Errorf("unable to look up leader epoch for %s[%d]: %w", t.Topic, p.Partition, err)
+				}
+				progressed = true
+
+				// If there is no prior epoch, or our offset is at
+				// or past the end of the prior epoch, our candidate
+				// is correct. The broker should always return an
+				// epoch prior to our candidate; we guard against a
+				// misbehaving broker to avoid looping forever.
+				if p.LeaderEpoch < 0 || p.EndOffset < 0 || l.offset >= p.EndOffset || p.LeaderEpoch >= l.epoch {
+					resolve(t.Topic, p.Partition, l)
+					continue
+				}
+				l.epoch = p.LeaderEpoch
+				if l.epoch == 0 {
+					resolve(t.Topic, p.Partition, l)
+				}
+			}
+		}
+		if !progressed {
+			return nil, errors.New("unable to look up leader epochs: OffsetForLeaderEpoch response did not contain any requested partitions")
+		}
+	}
+
+	return resolved, nil
+}
+
 func (cl *Client) setOffsets(setOffsets map[string]map[int32]EpochOffset, log bool) {
 	if len(setOffsets) == 0 {
 		return
@@ -522,7 +883,8 @@ const (
 	// fetches for "assigned" (actually lost) partitions. This additionally
 	// drops all buffered fetches, because they could contain partitions we
 	// lost. Thus, with this option, the actual offset in the map is
-	// meaningless / a dummy offset.
+	// meaningless / a dummy offset. The offsets the matching partitions
+	// were at are saved in lastInvalidated.
 	assignInvalidateMatching
 
 	// The counterpart to assignInvalidateMatching, assignSetMatching
@@ -544,6 +906,18 @@ func (h assignHow) String() string {
 	return ""
 }
 
+// saveInvalidated, called under the consumer's mu, saves the offset a
+// partition was at when assignInvalidateMatching unassigned it.
+func (c *consumer) saveInvalidated(topic string, partition int32, o Offset) {
+	if c.lastInvalidated == nil {
+		c.lastInvalidated = make(map[string]map[int32]Offset)
+	}
+	if c.lastInvalidated[topic] == nil {
+		c.lastInvalidated[topic] = make(map[int32]Offset)
+	}
+	c.lastInvalidated[topic][partition] = o
+}
+
 type fmtAssignment map[string]map[int32]Offset
 
 func (f fmtAssignment) String() string {
@@ -607,13 +981,15 @@ func (c *consumer) assignPartitions(assignments map[string]map[int32]Offset, how
 		session.decWorker()
 	}()
 
+	c.lastDiscarded = nil
+	c.lastInvalidated = nil
 	if how == assignWithoutInvalidating {
 		// Guarding a session change can actually create a new session
 		// if we had no session before, which is why we need to pass in
 		// our topicPartitions.
 		session = c.guardSessionChange(tps)
 	} else {
-		loadOffsets, _ = c.stopSession()
+		loadOffsets, _, c.lastDiscarded = c.stopSession()
 
 		// First, over all cursors currently in use, we unset them or set them
 		// directly as appropriate. Anything we do not unset, we keep.
@@ -628,6 +1004,10 @@ func (c *consumer) assignPartitions(assignments map[string]map[int32]Offset, how
 				if assignTopic, ok := assignments[usedCursor.topic]; ok {
 					if assignPart, ok := assignTopic[usedCursor.partition]; ok {
 						if how == assignInvalidateMatching {
+							c.saveInvalidated(usedCursor.topic, usedCursor.partition, Offset{
+								at:    usedCursor.offset,
+								epoch: usedCursor.lastConsumedEpoch,
+							})
 							usedCursor.unset()
 							shouldKeep = false
 						} else { // how == assignSetMatching
@@ -657,18 +1037,33 @@ func (c *consumer) assignPartitions(assignments map[string]map[int32]Offset, how
 		switch how {
 		case assignInvalidateAll:
 			loadOffsets = listOrEpochLoads{}
+			c.pausedOffsets = nil // everything is reassigned from scratch
 		case assignSetMatching:
 			// We had not yet loaded this partition, so there is
-			// nothing to set, and we keep everything.
+			// nothing to set, and we keep everything. Paused
+			// partitions are set for when they are resumed.
+			for t, ps := range assignments {
+				for p, o := range ps {
+					if _, ok := c.pausedOffsets[t][p]; ok {
+						c.pausedOffsets[t][p] = o
+					}
+				}
+			}
 		case assignInvalidateMatching:
-			loadOffsets.keepFilter(func(t string, p int32) bool {
+			loadOffsets.keepFilter(func(t string, p int32, load offsetLoad) bool {
 				if assignTopic, ok := assignments[t]; ok {
 					if _, ok := assignTopic[p]; ok {
+						c.saveInvalidated(t, p, load.Offset)
 						return false
 					}
 				}
 				return true
 			})
+			for t, ps := range assignments {
+				for p := range ps {
+					delete(c.pausedOffsets[t], p)
+				}
+			}
 		}
 	}
 
@@ -901,14 +1296,14 @@ func (l listOrEpochLoads) each(fn func(string, int32)) {
 	}
 }
 
-func (l *listOrEpochLoads) keepFilter(keep func(string, int32) bool) {
+func (l *listOrEpochLoads) keepFilter(keep func(string, int32, offsetLoad) bool) {
 	for _, m := range []offsetLoadMap{
 		l.List,
 		l.Epoch,
 	} {
 		for t, ps := range m {
-			for p := range ps {
-				if !keep(t, p) {
+			for p, load := range ps {
+				if !keep(t, p, load) {
 					delete(ps, p)
 					if len(ps) == 0 {
 						delete(m, t)
@@ -1132,14 +1527,15 @@ func (c *consumer) unguardSessionChange(session *consumerSession) {
 // Stops an active consumer session if there is one, and does not return until
 // all fetching, listing, offset for leader epoching is complete. This
 // invalidates any buffered fetches for the previous session and returns any
-// partitions that were listing offsets or loading epochs.
-func (c *consumer) stopSession() (listOrEpochLoads, *topicsPartitions) {
+// partitions that were listing offsets or loading epochs, as well as how many
+// buffered records were discarded per partition.
+func (c *consumer) stopSession() (listOrEpochLoads, *topicsPartitions, map[string]map[int32]int) {
 	c.sessionChangeMu.Lock()
 
 	session := c.loadSession()
 
 	if session == noConsumerSession {
-		return listOrEpochLoads{}, noTopicsPartitions // we had no session
+		return listOrEpochLoads{}, noTopicsPartitions, nil // we had no session
 	}
 
 	// Before storing noConsumerSession, cancel our old. This pairs
@@ -1174,8 +1570,25 @@ func (c *consumer) stopSession() (listOrEpochLoads, *topicsPartitions) {
 
 	c.sourcesReadyMu.Lock()
 	defer c.sourcesReadyMu.Unlock()
+	var discarded map[string]map[int32]int
 	for _, ready := range c.sourcesReadyForDraining {
-		ready.discardBuffered()
+		f := ready.discardBuffered()
+		for i := range f.Topics {
+			t := &f.Topics[i]
+			for j := range t.Partitions {
+				p := &t.Partitions[j]
+				if len(p.Records) == 0 {
+					continue
+				}
+				if discarded == nil {
+					discarded = make(map[string]map[int32]int)
+				}
+				if discarded[t.Topic] == nil {
+					discarded[t.Topic] = make(map[int32]int)
+				}
+				discarded[t.Topic][p.Partition] += len(p.Records)
+			}
+		}
 	}
 	c.sourcesReadyForDraining = nil
 
@@ -1184,7 +1597,7 @@ func (c *consumer) stopSession() (listOrEpochLoads, *topicsPartitions) {
 	// can act on errors. The session is dead.
 
 	session.listOrEpochLoadsWaiting.mergeFrom(session.listOrEpochLoadsLoading)
-	return session.listOrEpochLoadsWaiting, session.tps
+	return session.listOrEpochLoadsWaiting, session.tps, discarded
 }
 
 // Starts a new consumer session, allowing fetches to happen.
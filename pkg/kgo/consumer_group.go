@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/twmb/franz-go/pkg/kerr"
@@ -14,6 +15,11 @@ import (
 )
 
 type groupConsumer struct {
+	// unstableWait is the total nanoseconds spent waiting to retry
+	// fetching offsets due to UnstableOffsetCommit. This is first to
+	// ensure 64 bit alignment for atomic access.
+	unstableWait int64
+
 	c   *consumer // used to change consumer state; generally c.mu is grabbed on access
 	cl  *Client   // used for running requests / adding to topics map
 	cfg *cfg
@@ -31,13 +37,17 @@ type groupConsumer struct {
 
 	reSeen map[string]bool // topics we evaluated against regex, and whether we want them or not
 
+	// excluded is topics the user stopped consuming with
+	// ExcludeGroupTopics. Like reSeen, this is guarded by the consumer mu.
+	excluded map[string]struct{}
+
 	// Full lock grabbed in CommitOffsetsSync, read lock grabbed in
 	// CommitOffsets, this lock ensures that only one sync commit can
 	// happen at once, and if it is happening, no other commit can be
 	// happening.
 	syncCommitMu sync.RWMutex
 
-	rejoinCh chan string // cap 1; sent to if subscription changes (regex)
+	rejoinCh chan RejoinReason // cap 1; sent to if subscription changes (regex)
 
 	// For EOS, before we commit, we force a heartbeat. If the client and
 	// group member are both configured properly, then the transactional
@@ -67,6 +77,43 @@ type groupConsumer struct {
 	//  - read on metadata updates in findNewAssignments
 	leader atomicBool
 
+	// requireStable is initialized from RequireStableFetchOffsets and can
+	// be toggled at runtime with SetRequireStable.
+	requireStable atomicBool
+
+	// releaseInstanceID releases our instance ID, if any, from the
+	// process wide registry once we leave the group.
+	releaseInstanceID func()
+
+	// revokeAll is set in Rejoin to have the heartbeat loop revoke all
+	// partitions before rejoining, even for cooperative consumers. This
+	// is cleared when the heartbeat loop begins revoking.
+	revokeAll atomicBool
+
+	// If autocommitting on the first poll, firstCommittable is closed once
+	// the first time offsets become available to autocommit.
+	firstCommittable     chan struct{}
+	firstCommittableOnce sync.Once
+
+	// commitQueueSem bounds the number of commits queued with
+	// CommitOffsetsQueued, and commitQueueTail is closed once the most
+	// recently queued commit is done. Each queued commit waits for the
+	// commit queued before it.
+	commitQueueSem  chan struct{}
+	commitQueueMu   sync.Mutex
+	commitQueueTail chan struct{}
+
+	// rebalanceBlocked is closed in AllowRebalance after a BlockRebalance;
+	// revoking waits on it before calling onRevoked. This is nil if
+	// rebalancing is not blocked.
+	rebalanceMu      sync.Mutex
+	rebalanceBlocked chan struct{}
+
+	// fetchedOffsets is set to true once offsets have been fetched and
+	// assigned once. This is only used in fetchOffsets, which is
+	// serialized across group sessions.
+	fetchedOffsets bool
+
 	// Set to true when ending a transaction committing transaction
 	// offsets, and then set to false immediately after before calling
 	// EndTransaction.
@@ -105,6 +152,63 @@ type groupConsumer struct {
 	memberID   string
 	generation int32
 
+	// lastRejoinReason is set when a group session ends, and read when
+	// joining and in LastRejoinReason.
+	lastRejoinReason RejoinReason
+
+	// bgRevokeDone is non-nil and closed once an eager revoke running in
+	// the background (BackgroundRevoke) finishes.
+	bgRevokeDone chan struct{}
+
+	// pausedTopics are topics paused with PauseTopics; heads for these
+	// topics do not advance until the topics are resumed.
+	pausedTopics map[string]struct{}
+
+	// lastCommitErrs is the per-partition errors from the most recent
+	// commit that was not canceled, or nil if it had no errors.
+	lastCommitErrs map[string]map[int32]error
+
+	// lastBalancePlan is the member => topic => partitions plan from
+	// the last time this member balanced the group as leader, or nil if
+	// this member last joined as a follower.
+	lastBalancePlan map[string]map[string][]int32
+
+	// nextRebalanceTimeout, if non-zero, is a one-shot rebalance timeout
+	// used by the next join instead of the configured timeout; see
+	// OverrideNextRebalanceTimeout. rebalanceTimeout is the timeout
+	// most recently sent in a join, or zero if we have not joined.
+	nextRebalanceTimeout time.Duration
+	rebalanceTimeout     time.Duration
+
+	// newPartitionsRejoinPending is set while a delayed leader rejoin
+	// for new partitions is waiting; see NewPartitionsRejoinDelay.
+	newPartitionsRejoinPending bool
+
+	// subscriptionRejoinSeq is bumped every time a subscription change
+	// would rejoin; only the latest debounced rejoin fires. See
+	// SubscriptionRejoinDebounce.
+	subscriptionRejoinSeq uint64
+
+	// memberCount is the number of members in the group as of
+	// memberCountGeneration. The leader sets this when joining from the
+	// join response; followers set this from a DescribeGroups request
+	// issued from GroupMemberCount. memberCountDone is non-nil while
+	// that request is in flight, and is closed once it finishes.
+	memberCount           int
+	memberCountGeneration int32
+	memberCountDone       chan struct{}
+
+	// stable is closed once a group session has begun heartbeating and
+	// has fetched offsets for all partitions added in the session. When
+	// the session ends (or first errors), a new open channel replaces the
+	// closed one.
+	stable       chan struct{}
+	stableClosed bool
+
+	// sessionAssigned is the current session's assignDone, closed once
+	// OnPartitionsAssigned has returned for the session.
+	sessionAssigned <-chan struct{}
+
 	// commitCancel and commitDone are set under mu before firing off an
 	// async commit request. If another commit happens, it cancels the
 	// prior commit, waits for the prior to be done, and then starts its
@@ -112,6 +216,12 @@ type groupConsumer struct {
 	commitCancel func()
 	commitDone   chan struct{}
 
+	// partitionCommits is the per-partition counterpart of commitCancel
+	// and commitDone for CommitRecordsPerPartition: commits for the same
+	// partition cancel and wait for the prior, while commits for
+	// different partitions run concurrently.
+	partitionCommits map[string]map[int32]*partitionCommit
+
 	// blockAuto is set and cleared in CommitOffsets{,Sync} to block
 	// autocommitting if autocommitting is active. This ensures that an
 	// autocommit does not cancel the user's manual commit.
@@ -144,6 +254,52 @@ func (cl *Client) LeaveGroup() {
 	c.mu.Unlock()
 
 	wait() // wait after we unlock
+	c.g.releaseInstanceID()
+}
+
+// instanceIDs tracks the group instance IDs of all clients in this process
+// that are in a group, so that we can warn about duplicates: two members with
+// the same instance ID fence each other.
+var instanceIDs struct {
+	mu    sync.Mutex
+	inUse map[[2]string]int // group, instance ID => number of clients
+}
+
+// claimInstanceID registers our instance ID, if any, warning if another
+// client in this process already uses the same ID for the same group. The
+// returned function releases the claim and is safe to call multiple times.
+func claimInstanceID(cfg *cfg) func() {
+	if cfg.instanceID == nil {
+		return func() {}
+	}
+	key := [2]string{cfg.group, *cfg.instanceID}
+
+	instanceIDs.mu.Lock()
+	if instanceIDs.inUse == nil {
+		instanceIDs.inUse = make(map[[2]string]int)
+	}
+	prior := instanceIDs.inUse[key]
+	instanceIDs.inUse[key]++
+	instanceIDs.mu.Unlock()
+
+	if prior > 0 {
+		cfg.logger.Log(LogLevelWarn, "another client in this process is using the same group instance ID for the same group; the two clients will fence each other with FENCED_INSTANCE_ID errors",
+			"group", cfg.group,
+			"instance_id", *cfg.instanceID,
+			"clients_using", prior+1,
+		)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			instanceIDs.mu.Lock()
+			defer instanceIDs.mu.Unlock()
+			if instanceIDs.inUse[key]--; instanceIDs.inUse[key] <= 0 {
+				delete(instanceIDs.inUse, key)
+			}
+		})
+	}
 }
 
 func (c *consumer) initGroup() {
@@ -156,32 +312,39 @@ func (c *consumer) initGroup() {
 		ctx:    ctx,
 		cancel: cancel,
 
-		reSeen: make(map[string]bool),
+		reSeen:   make(map[string]bool),
+		excluded: make(map[string]struct{}),
 
 		manageDone:       make(chan struct{}),
 		cooperative:      c.cl.cfg.cooperative(),
 		tps:              newTopicsPartitions(),
-		rejoinCh:         make(chan string, 1),
+		rejoinCh:         make(chan RejoinReason, 1),
 		heartbeatForceCh: make(chan func(error)),
 		using:            make(map[string]int),
+
+		memberCountGeneration: -1,
+		stable:                make(chan struct{}),
+		commitQueueSem:        make(chan struct{}, c.cl.cfg.commitQueueDepth),
 	}
 	c.g = g
+	g.requireStable.set(g.cfg.requireStable)
+	g.releaseInstanceID = claimInstanceID(g.cfg)
+	g.memberID = g.cfg.memberID
 	if !g.cfg.setCommitCallback {
 		g.cfg.commitCallback = g.defaultCommitCallback
 	}
 
 	if g.cfg.txnID == nil {
-		// We only override revoked / lost if they were not explicitly
-		// set by options.
-		if !g.cfg.setRevoked {
+		// We only override revoked if it was not explicitly set by
+		// options. There is no default onLost: partitions that are
+		// lost are no longer ours, so we never commit for them, and
+		// we never fall back to onRevoked.
+		switch {
+		case g.cfg.onRevokedCommit != nil:
+			g.cfg.onRevoked = g.revokeCommit
+		case !g.cfg.setRevoked:
 			g.cfg.onRevoked = g.defaultRevoke
 		}
-		// For onLost, we do not want to commit in onLost, so we
-		// explicitly set onLost to an empty function to avoid the
-		// fallback to onRevoked.
-		if !g.cfg.setLost {
-			g.cfg.onLost = func(context.Context, *Client, map[string][]int32) {}
-		}
 	} else {
 		g.cfg.autocommitDisable = true
 	}
@@ -211,9 +374,13 @@ func (c *consumer) initGroup() {
 			if user != nil {
 				dup := make(map[string][]int32)
 				for k, vs := range m {
-					dup[k] = append([]int32(nil), vs...)
+					vs = append([]int32(nil), vs...)
+					sort.Slice(vs, func(i, j int) bool { return vs[i] < vs[j] })
+					dup[k] = vs
 				}
+				start := time.Now()
 				user(ctx, cl, dup)
+				g.callbackDone(name, time.Since(start))
 			}
 		}
 	}
@@ -230,6 +397,9 @@ func (c *consumer) initGroup() {
 	}
 
 	if !g.cfg.autocommitDisable && g.cfg.autocommitInterval > 0 {
+		if g.cfg.autocommitFirstPoll {
+			g.firstCommittable = make(chan struct{})
+		}
 		g.cfg.logger.Log(LogLevelInfo, "beginning autocommit loop", "group", g.cfg.group)
 		go g.loopCommit()
 	}
@@ -260,37 +430,7 @@ func (g *groupConsumer) manage() {
 			continue
 		}
 
-		hook := func() {
-			g.cfg.hooks.each(func(h Hook) {
-				if h, ok := h.(HookGroupManageError); ok {
-					h.OnGroupManageError(err)
-				}
-			})
-		}
-
-		if err == context.Canceled && g.cfg.onRevoked != nil {
-			// The cooperative consumer does not revoke everything
-			// while rebalancing, meaning if our context is
-			// canceled, we may have uncommitted data. Rather than
-			// diving into OnLost, we should go into OnRevoked,
-			// because for the most part, a context cancelation
-			// means we are leaving the group. Going into OnRevoked
-			// gives us an opportunity to commit outstanding
-			// offsets. For the eager consumer, since we always
-			// revoke before exiting the heartbeat loop, we do not
-			// really care so much about *needing* to call
-			// onRevoked, but since we are handling this case for
-			// the cooperative consumer we may as well just also
-			// include the eager consumer.
-			g.cfg.onRevoked(g.cl.ctx, g.cl, g.nowAssigned)
-		} else {
-			// Any other error is perceived as a fatal error,
-			// and we go into OnLost as appropriate.
-			if g.cfg.onLost != nil {
-				g.cfg.onLost(g.cl.ctx, g.cl, g.nowAssigned)
-			}
-			hook()
-		}
+		g.endManageSession(err)
 
 		// We need to invalidate everything from an error return.
 		{
@@ -312,26 +452,117 @@ func (g *groupConsumer) manage() {
 			return
 		}
 
+		// If another member with our instance ID fenced us, every
+		// retry is fenced as well; we stop managing rather than
+		// spinning in the backoff loop below.
+		if err == kerr.FencedInstanceID {
+			var instanceID string
+			if g.cfg.instanceID != nil {
+				instanceID = *g.cfg.instanceID
+			}
+			g.cfg.logger.Log(LogLevelError, "group member was fenced by another member with the same instance id, stopping group management",
+				"group", g.cfg.group,
+				"instance_id", instanceID,
+			)
+			g.cfg.hooks.each(func(h Hook) {
+				if h, ok := h.(HookGroupInstanceFenced); ok {
+					h.OnInstanceFenced(g.cfg.group, instanceID)
+				}
+			})
+			return
+		}
+
+		// A balancer that assigns topics we did not ask for will do
+		// so again on every rejoin; if the user opted into failing
+		// on this, we stop managing.
+		if ue, ok := err.(*ErrUnrequestedTopicAssigned); ok {
+			g.cfg.logger.Log(LogLevelError, "group member was assigned topics it did not ask for, stopping group management",
+				"group", g.cfg.group,
+				"topics", ue.Topics,
+			)
+			return
+		}
+
 		// Waiting for the backoff is a good time to update our
 		// metadata; maybe the error is from stale metadata.
 		consecutiveErrors++
-		backoff := g.cfg.retryBackoff(consecutiveErrors)
+		backoff := g.rebalanceBackoff(consecutiveErrors)
 		g.cfg.logger.Log(LogLevelError, "join and sync loop errored",
 			"group", g.cfg.group,
 			"err", err,
 			"consecutive_errors", consecutiveErrors,
 			"backoff", backoff,
 		)
-		deadline := time.Now().Add(backoff)
+		deadline := g.cfg.clock.Now().Add(backoff)
 		g.cl.waitmeta(g.ctx, backoff, "waitmeta during join & sync error backoff")
-		after := time.NewTimer(time.Until(deadline))
 		select {
 		case <-g.ctx.Done():
-			after.Stop()
 			return
-		case <-after.C:
+		case <-g.cfg.clock.After(deadline.Sub(g.cfg.clock.Now())):
+		}
+	}
+}
+
+// endManageSession is called when a group management session ends with err:
+// if we are leaving the group, we revoke what we still own, and otherwise we
+// have lost everything.
+func (g *groupConsumer) endManageSession(err error) {
+	g.waitBackgroundRevoke()
+
+	if err == context.Canceled {
+		// A context cancelation means we are leaving the group: we
+		// still own our partitions until we leave, so we go into
+		// OnRevoked rather than OnLost to give an opportunity to
+		// commit outstanding offsets.
+		//
+		// If the cancelation happened while heartbeating, the
+		// heartbeat loop already revoked everything and nowAssigned
+		// is nil. If the cancelation happened while joining or
+		// syncing, the cooperative consumer may still have partitions
+		// from the prior session that we need to revoke here.
+		if len(g.nowAssigned) > 0 {
+			g.revoke(revokeThisSession, nil, revokeAllLeaving)
 		}
+		return
+	}
+
+	// Any other error is perceived as a fatal error: our partitions are
+	// gone and we go into OnLost.
+	g.cfg.onLost(g.cl.ctx, g.cl, g.nowAssigned)
+	g.cfg.hooks.each(func(h Hook) {
+		if h, ok := h.(HookGroupManageError); ok {
+			h.OnGroupManageError(err)
+		}
+	})
+	g.setRejoinReason(RejoinReasonSessionError)
+}
+
+// callbackDone calls HookGroupCallbackDuration with how long a user
+// onAssigned, onRevoked, or onLost callback took, and warns if the callback
+// took a large fraction of the session timeout.
+func (g *groupConsumer) callbackDone(name string, d time.Duration) {
+	g.cfg.hooks.each(func(h Hook) {
+		if h, ok := h.(HookGroupCallbackDuration); ok {
+			h.OnGroupCallbackDuration(name, d)
+		}
+	})
+	if f := g.cfg.slowCallbackFraction; f > 0 && d > time.Duration(f*float64(g.cfg.sessionTimeout)) {
+		g.cfg.logger.Log(LogLevelWarn, "group callback took a large fraction of the session timeout, slow callbacks can cause rebalance loops",
+			"group", g.cfg.group,
+			"callback", name,
+			"took", d,
+			"session_timeout", g.cfg.sessionTimeout,
+		)
+	}
+}
+
+// rebalanceBackoff returns how long to backoff before rejoining the group,
+// using RebalanceRetryBackoffFn if set.
+func (g *groupConsumer) rebalanceBackoff(tries int) time.Duration {
+	if g.cfg.rebalanceBackoff != nil {
+		return g.cfg.rebalanceBackoff(tries)
 	}
+	return g.cfg.retryBackoff(tries)
 }
 
 func (g *groupConsumer) leave() (wait func()) {
@@ -356,6 +587,15 @@ func (g *groupConsumer) leave() (wait func()) {
 			<-g.manageDone
 		}
 
+		// We are dying, so no new member count describe can begin;
+		// we wait for any in flight describe, which is canceled.
+		g.mu.Lock()
+		memberCountDone := g.memberCountDone
+		g.mu.Unlock()
+		if memberCountDone != nil {
+			<-memberCountDone
+		}
+
 		if wasDead {
 			// If we already called leave(), then we just wait for
 			// the prior leave to finish and we avoid re-issuing a
@@ -383,8 +623,56 @@ func (g *groupConsumer) leave() (wait func()) {
 	return func() { <-done }
 }
 
-// returns the difference of g.nowAssigned and g.lastAssigned.
+// leaveStuckRebalance is called if joining and syncing takes longer than
+// LeaveOnStuckRebalance allows: we leave the group and drop our member ID so
+// that the manage loop rejoins fresh.
+func (g *groupConsumer) leaveStuckRebalance(waited time.Duration) error {
+	g.mu.Lock()
+	memberID := g.memberID
+	g.memberID = ""
+	g.mu.Unlock()
+
+	g.cfg.logger.Log(LogLevelWarn, "group rebalance is stuck, leaving the group to rejoin with a new member id",
+		"group", g.cfg.group,
+		"member_id", memberID,
+		"waited", waited,
+	)
+	g.cfg.hooks.each(func(h Hook) {
+		if h, ok := h.(HookGroupRebalanceStuck); ok {
+			h.OnGroupRebalanceStuck(g.cfg.group, memberID, waited)
+		}
+	})
+
+	if memberID != "" {
+		req := kmsg.NewPtrLeaveGroupRequest()
+		req.Group = g.cfg.group
+		req.MemberID = memberID
+		member := kmsg.NewLeaveGroupRequestMember()
+		member.MemberID = memberID
+		member.InstanceID = g.cfg.instanceID
+		req.Members = append(req.Members, member)
+		ctx, cancel := context.WithTimeout(g.ctx, g.cfg.sessionTimeout)
+		_, err := req.RequestWith(ctx, g.cl)
+		cancel()
+		if err != nil {
+			g.cfg.logger.Log(LogLevelWarn, "unable to leave the group after a stuck rebalance, rejoining anyway", "group", g.cfg.group, "err", err)
+		}
+	}
+	return errRebalanceStuck
+}
+
+// returns the difference of g.nowAssigned and g.lastAssigned, with the
+// partitions for each topic sorted so that logs and callbacks are
+// deterministic.
 func (g *groupConsumer) diffAssigned() (added, lost map[string][]int32) {
+	defer func() {
+		for _, m := range []map[string][]int32{added, lost} {
+			for _, partitions := range m {
+				sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+			}
+		}
+	}()
+
 	if g.lastAssigned == nil {
 		return g.nowAssigned, nil
 	}
@@ -435,6 +723,110 @@ func (g *groupConsumer) diffAssigned() (added, lost map[string][]int32) {
 	return added, lost
 }
 
+// BlockRebalance blocks any group rebalance from proceeding into
+// OnPartitionsRevoked until AllowRebalance is called. This can be used to
+// reach a safe checkpoint in processing before partitions are revoked, rather
+// than doing all checkpointing work within OnPartitionsRevoked.
+//
+// Heartbeating continues while a rebalance is blocked, but Kafka only waits
+// for group members to rejoin until the rebalance timeout. To avoid being
+// kicked from the group, a blocked rebalance proceeds anyway once half of the
+// group's rebalance timeout elapses, leaving the other half for revoking and
+// rejoining. Rebalancing should be allowed as soon as possible.
+//
+// Calling BlockRebalance while rebalancing is already blocked is a no-op. This
+// function does nothing if the client is not consuming as a group.
+func (cl *Client) BlockRebalance() {
+	g := cl.consumer.g
+	if g == nil {
+		return
+	}
+	g.rebalanceMu.Lock()
+	defer g.rebalanceMu.Unlock()
+	if g.rebalanceBlocked == nil {
+		g.rebalanceBlocked = make(chan struct{})
+	}
+}
+
+// AllowRebalance allows a rebalance that was blocked with BlockRebalance to
+// proceed. Calling AllowRebalance when rebalancing is not blocked is a no-op.
+func (cl *Client) AllowRebalance() {
+	g := cl.consumer.g
+	if g == nil {
+		return
+	}
+	g.rebalanceMu.Lock()
+	defer g.rebalanceMu.Unlock()
+	if g.rebalanceBlocked != nil {
+		close(g.rebalanceBlocked)
+		g.rebalanceBlocked = nil
+	}
+}
+
+// waitRebalanceAllowed waits until rebalancing is not blocked, half of the
+// rebalance timeout elapses, or the client is closed. We only wait half so
+// that after waiting, we still have time to revoke and rejoin before Kafka
+// boots us from the group.
+func (g *groupConsumer) waitRebalanceAllowed() {
+	g.rebalanceMu.Lock()
+	blocked := g.rebalanceBlocked
+	g.rebalanceMu.Unlock()
+	if blocked == nil {
+		return
+	}
+
+	g.mu.Lock()
+	maxWait := g.effectiveRebalanceTimeout() / 2
+	g.mu.Unlock()
+
+	g.cfg.logger.Log(LogLevelInfo, "rebalance is blocked, waiting for AllowRebalance before revoking", "group", g.cfg.group)
+	select {
+	case <-blocked:
+		g.cfg.logger.Log(LogLevelInfo, "rebalance allowed, continuing to revoke", "group", g.cfg.group)
+	case <-g.cfg.clock.After(maxWait):
+		g.cfg.logger.Log(LogLevelWarn, "rebalance was blocked for longer than half the rebalance timeout, continuing to revoke", "group", g.cfg.group, "max_wait", maxWait)
+	case <-g.cl.ctx.Done():
+	}
+}
+
+// revokeDiscarded logs and calls HookGroupRevokeDiscarded with the buffered
+// records that were discarded when invalidating fetches before revoking.
+func (g *groupConsumer) revokeDiscarded(discarded map[string]map[int32]int) {
+	if len(discarded) > 0 {
+		g.cfg.logger.Log(LogLevelInfo, "discarded buffered records before revoking", "group", g.cfg.group, "discarded", discarded)
+	}
+	g.cfg.hooks.each(func(h Hook) {
+		if h, ok := h.(HookGroupRevokeDiscarded); ok {
+			h.OnGroupRevokeDiscarded(discarded)
+		}
+	})
+}
+
+// revokeCtx returns the context to pass to onRevoked. We use the client's
+// context rather than the group context, because revoking could come from the
+// group being left, at which point the group context is already canceled. If
+// a revoke timeout is configured, the context is additionally bounded so that
+// a hung commit does not block the session from ending.
+func (g *groupConsumer) revokeCtx() (context.Context, context.CancelFunc) {
+	if g.cfg.revokeTimeout <= 0 {
+		return g.cl.ctx, func() {}
+	}
+	return context.WithTimeout(g.cl.ctx, g.cfg.revokeTimeout)
+}
+
+// waitBackgroundRevoke waits for any OnPartitionsRevoked that is running in
+// the background due to BackgroundRevoke.
+func (g *groupConsumer) waitBackgroundRevoke() {
+	g.mu.Lock()
+	done := g.bgRevokeDone
+	g.bgRevokeDone = nil
+	g.mu.Unlock()
+	if done != nil {
+		g.cfg.logger.Log(LogLevelDebug, "waiting for background revoke to finish", "group", g.cfg.group)
+		<-done
+	}
+}
+
 type revokeStage int8
 
 const (
@@ -442,6 +834,16 @@ const (
 	revokeThisSession
 )
 
+// revokeAllReason is why a consumer revokes everything it owns rather than only
+// what a cooperative consumer lost.
+type revokeAllReason int8
+
+const (
+	revokeNotAll       revokeAllReason = iota
+	revokeAllLeaving                   // the group is being left
+	revokeAllRejoining                 // Rejoin requested a full rejoin
+)
+
 // revoke calls onRevoked for partitions that this group member is losing and
 // updates the uncommitted map after the revoke.
 //
@@ -450,33 +852,70 @@ const (
 //
 // For cooperative consumers, this either
 //
-//     (1) if revoking lost partitions from a prior session (i.e., after sync),
-//         this revokes the passed in lost
-//     (2) if revoking at the end of a session, this revokes topics that the
-//         consumer is no longer interested in consuming (TODO, actually, only
-//         once we allow subscriptions to change without leaving the group).
+//	(1) if revoking lost partitions from a prior session (i.e., after sync),
+//	    this revokes the passed in lost
+//	(2) if revoking at the end of a session, this revokes topics that the
+//	    consumer is no longer interested in consuming (i.e., topics
+//	    excluded with ExcludeGroupTopics).
 //
 // Lastly, for cooperative consumers, this must selectively delete what was
 // lost from the uncommitted map.
-func (g *groupConsumer) revoke(stage revokeStage, lost map[string][]int32, leaving bool) {
-	if !g.cooperative || leaving { // stage == revokeThisSession if not cooperative
+func (g *groupConsumer) revoke(stage revokeStage, lost map[string][]int32, all revokeAllReason) {
+	g.waitBackgroundRevoke()
+
+	if !g.cooperative || all != revokeNotAll { // stage == revokeThisSession if not cooperative
 		// If we are an eager consumer, we stop fetching all of our
 		// current partitions as we will be revoking them.
 		g.c.mu.Lock()
-		if leaving {
+		switch all {
+		case revokeAllLeaving:
 			g.c.assignPartitions(nil, assignInvalidateAll, nil, "revoking all assignments because we are leaving the group")
-		} else {
+		case revokeAllRejoining:
+			g.c.assignPartitions(nil, assignInvalidateAll, nil, "revoking all assignments because we are fully rejoining the group")
+		default:
 			g.c.assignPartitions(nil, assignInvalidateAll, nil, "revoking all assignments because we are not cooperative")
 		}
+		discarded := g.c.lastDiscarded
 		g.c.mu.Unlock()
+		g.revokeDiscarded(discarded)
 
-		if !g.cooperative {
+		switch {
+		case !g.cooperative:
 			g.cfg.logger.Log(LogLevelInfo, "eager consumer revoking prior assigned partitions", "group", g.cfg.group, "revoking", g.nowAssigned)
-		} else {
-			g.cfg.logger.Log(LogLevelInfo, "cooperative consumer revoking prior assigned partitions because leaving group", "group", g.cfg.group, "revoking", g.nowAssigned)
+		case all == revokeAllLeaving:
+			g.cfg.logger.Log(LogLevelInfo, "cooperative consumer revoking all prior assigned partitions because leaving group", "group", g.cfg.group, "revoking", g.nowAssigned)
+		default:
+			g.cfg.logger.Log(LogLevelInfo, "cooperative consumer revoking all prior assigned partitions because fully rejoining", "group", g.cfg.group, "revoking", g.nowAssigned)
+		}
+		if g.cfg.onRevoked != nil && g.cfg.backgroundRevoke && all == revokeNotAll {
+			// We rejoin while revoking, and wait for the revoke
+			// to finish before fetching offsets in the next
+			// session. We only clear uncommitted once the revoke
+			// is done so that it can still commit.
+			revoking := g.nowAssigned
+			done := make(chan struct{})
+			g.mu.Lock()
+			g.bgRevokeDone = done
+			g.mu.Unlock()
+			g.nowAssigned = nil
+			go func() {
+				defer close(done)
+				g.waitRebalanceAllowed()
+				ctx, cancel := g.revokeCtx()
+				g.cfg.onRevoked(ctx, g.cl, revoking)
+				cancel()
+
+				g.mu.Lock()
+				g.uncommitted = nil
+				g.mu.Unlock()
+			}()
+			return
 		}
 		if g.cfg.onRevoked != nil {
-			g.cfg.onRevoked(g.cl.ctx, g.cl, g.nowAssigned)
+			g.waitRebalanceAllowed()
+			ctx, cancel := g.revokeCtx()
+			g.cfg.onRevoked(ctx, g.cl, g.nowAssigned)
+			cancel()
 		}
 		g.nowAssigned = nil
 
@@ -497,11 +936,11 @@ func (g *groupConsumer) revoke(stage revokeStage, lost map[string][]int32, leavi
 
 	case revokeThisSession:
 		// lost is nil for cooperative assigning. Instead, we determine
-		// lost by finding subscriptions we are no longer interested in.
-		//
-		// TODO only relevant when we allow reassigning with the same
-		// group to change subscriptions (also we must delete the
-		// unused partitions from nowAssigned).
+		// lost by finding assigned partitions of topics we are not
+		// using, which fetchOffsets skipped assigning. Revoking these
+		// removes them from nowAssigned, meaning we will no longer
+		// claim to own them when rejoining.
+		lost = g.deleteUnwantedAssigned()
 	}
 
 	if len(lost) > 0 {
@@ -528,7 +967,9 @@ func (g *groupConsumer) revoke(stage revokeStage, lost map[string][]int32, leavi
 		// after a revoke but before an invalidation.
 		g.c.mu.Lock()
 		g.c.assignPartitions(lostOffsets, assignInvalidateMatching, g.tps, "revoking assignments from cooperative consuming")
+		discarded := g.c.lastDiscarded
 		g.c.mu.Unlock()
+		g.revokeDiscarded(discarded)
 	}
 
 	if len(lost) > 0 || stage == revokeThisSession {
@@ -538,7 +979,10 @@ func (g *groupConsumer) revoke(stage revokeStage, lost map[string][]int32, leavi
 			g.cfg.logger.Log(LogLevelInfo, "cooperative consumer calling onRevoke", "group", g.cfg.group, "lost", lost, "stage", stage)
 		}
 		if g.cfg.onRevoked != nil {
-			g.cfg.onRevoked(g.cl.ctx, g.cl, lost)
+			g.waitRebalanceAllowed()
+			ctx, cancel := g.revokeCtx()
+			g.cfg.onRevoked(ctx, g.cl, lost)
+			cancel()
 		}
 	}
 
@@ -546,7 +990,7 @@ func (g *groupConsumer) revoke(stage revokeStage, lost map[string][]int32, leavi
 		return
 	}
 
-	defer g.rejoin("cooperative rejoin after revoking what we lost") // cooperative consumers rejoin after they revoking what they lost
+	defer g.rejoin(RejoinReasonCooperativeRevoke) // cooperative consumers rejoin after they revoking what they lost
 
 	// The block below deletes everything lost from our uncommitted map.
 	// All commits should be **completed** by the time this runs. An async
@@ -596,7 +1040,7 @@ func (s *assignRevokeSession) prerevoke(g *groupConsumer, lost map[string][]int3
 	go func() {
 		defer close(s.prerevokeDone)
 		if g.cooperative && len(lost) > 0 {
-			g.revoke(revokeLastSession, lost, false)
+			g.revoke(revokeLastSession, lost, revokeNotAll)
 		}
 	}()
 	return s.prerevokeDone
@@ -607,9 +1051,13 @@ func (s *assignRevokeSession) assign(g *groupConsumer, newAssigned map[string][]
 		defer close(s.assignDone)
 		<-s.prerevokeDone
 		if g.cfg.onAssigned != nil {
-			// We always call on assigned, even if nothing new is
-			// assigned. This allows consumers to know that
-			// assignment is done and do setup logic.
+			// By default, we always call on assigned, even if
+			// nothing new is assigned. This allows consumers to
+			// know that assignment is done and do setup logic.
+			if g.cfg.skipUnchangedAssigned && len(newAssigned) == 0 {
+				g.cfg.logger.Log(LogLevelDebug, "skipping OnPartitionsAssigned since nothing new was assigned", "group", g.cfg.group)
+				return
+			}
 			g.cfg.onAssigned(g.cl.ctx, g.cl, newAssigned)
 		}
 	}()
@@ -625,11 +1073,11 @@ func (s *assignRevokeSession) assign(g *groupConsumer, newAssigned map[string][]
 // This may not run before returning from the heartbeat loop: if we encounter a
 // fatal error, we return before revoking so that we can instead call onLost in
 // the manage loop.
-func (s *assignRevokeSession) revoke(g *groupConsumer, leaving bool) <-chan struct{} {
+func (s *assignRevokeSession) revoke(g *groupConsumer, all revokeAllReason) <-chan struct{} {
 	go func() {
 		defer close(s.revokeDone)
 		<-s.assignDone
-		g.revoke(revokeThisSession, nil, leaving)
+		g.revoke(revokeThisSession, nil, all)
 	}()
 	return s.revokeDone
 }
@@ -639,10 +1087,10 @@ func (s *assignRevokeSession) revoke(g *groupConsumer, leaving bool) <-chan stru
 // when heartbeating errors (or if fetch offsets errors).
 //
 // Before returning, this function ensures that
-//  - onAssigned is complete
-//    - which ensures that pre revoking is complete
-//  - fetching is complete
-//  - heartbeating is complete
+//   - onAssigned is complete
+//   - which ensures that pre revoking is complete
+//   - fetching is complete
+//   - heartbeating is complete
 func (g *groupConsumer) setupAssignedAndHeartbeat() error {
 	hbErrCh := make(chan error, 1)
 	fetchErrCh := make(chan error, 1)
@@ -650,6 +1098,11 @@ func (g *groupConsumer) setupAssignedAndHeartbeat() error {
 	s := newAssignRevokeSession()
 	added, lost := g.diffAssigned()
 	g.cfg.logger.Log(LogLevelInfo, "new group session begun", "group", g.cfg.group, "added", tpsFmt(added), "lost", tpsFmt(lost))
+	g.cfg.hooks.each(func(h Hook) {
+		if h, ok := h.(HookGroupSessionBegin); ok {
+			h.OnGroupSessionBegin(added, lost, g.generation)
+		}
+	})
 	s.prerevoke(g, lost) // for cooperative consumers
 
 	// Since we have joined the group, we immediately begin heartbeating.
@@ -662,6 +1115,12 @@ func (g *groupConsumer) setupAssignedAndHeartbeat() error {
 		hbErrCh <- g.heartbeat(fetchErrCh, s)
 	}()
 
+	// If the prior session's eager revoke is running in the background,
+	// we wait for it before fetching offsets so that we see what it
+	// committed, and so that OnPartitionsAssigned is not called
+	// concurrently with OnPartitionsRevoked.
+	g.waitBackgroundRevoke()
+
 	// We immediately begin fetching offsets. We want to wait until the
 	// fetch function returns, since it assumes within it that another
 	// assign cannot happen (it assigns partitions itself). Returning
@@ -672,13 +1131,23 @@ func (g *groupConsumer) setupAssignedAndHeartbeat() error {
 	// is specifically used for this function's return.
 	fetchDone := make(chan struct{})
 	defer func() { <-fetchDone }()
-	if len(added) > 0 {
+	defer g.setUnstable()
+	g.mu.Lock()
+	g.sessionAssigned = s.assignDone // set before we can become stable
+	g.mu.Unlock()
+	fetchAdded := g.fetchableAssigned(added)
+	if len(fetchAdded) > 0 {
 		go func() {
 			defer close(fetchDone)
 			defer close(fetchErrCh)
-			fetchErrCh <- g.fetchOffsets(ctx, added, lost)
+			err := g.fetchOffsets(ctx, fetchAdded, lost)
+			if err == nil {
+				g.setStable()
+			}
+			fetchErrCh <- err
 		}()
 	} else {
+		g.setStable()
 		close(fetchDone)
 		close(fetchErrCh)
 	}
@@ -705,6 +1174,35 @@ func (g *groupConsumer) setupAssignedAndHeartbeat() error {
 	return <-hbErrCh
 }
 
+// fetchableAssigned returns the subset of added partitions that we should
+// fetch, which is everything unless we are restricted with
+// FetchAssignedSubset.
+func (g *groupConsumer) fetchableAssigned(added map[string][]int32) map[string][]int32 {
+	allowed := g.cfg.fetchAssignedSubset
+	if allowed == nil {
+		return added
+	}
+	fetchable := make(map[string][]int32)
+	skipped := make(map[string][]int32)
+	for topic, partitions := range added {
+		allowedPartitions := make(map[int32]bool, len(allowed[topic]))
+		for _, partition := range allowed[topic] {
+			allowedPartitions[partition] = true
+		}
+		for _, partition := range partitions {
+			if allowedPartitions[partition] {
+				fetchable[topic] = append(fetchable[topic], partition)
+			} else {
+				skipped[topic] = append(skipped[topic], partition)
+			}
+		}
+	}
+	if len(skipped) > 0 {
+		g.cfg.logger.Log(LogLevelInfo, "not fetching assigned partitions that are not in the FetchAssignedSubset allowlist", "group", g.cfg.group, "skipped", tpsFmt(skipped))
+	}
+	return fetchable
+}
+
 // heartbeat issues heartbeat requests to Kafka for the duration of a group
 // session.
 //
@@ -715,8 +1213,27 @@ func (g *groupConsumer) setupAssignedAndHeartbeat() error {
 //
 // If the offset fetch is successful, then we basically sit in this function
 // until a heartbeat errors or we, being the leader, decide to re-join.
+//
+// The loop itself is in heartbeatLoop; we wrap it to call the heartbeat loop
+// start and end hooks.
 func (g *groupConsumer) heartbeat(fetchErrCh <-chan error, s *assignRevokeSession) error {
-	ticker := time.NewTicker(g.cfg.heartbeatInterval)
+	memberID, generation := g.memberID, g.generation
+	g.cfg.hooks.each(func(h Hook) {
+		if h, ok := h.(HookGroupHeartbeatLoopStart); ok {
+			h.OnGroupHeartbeatLoopStart(g.cfg.group, memberID, generation)
+		}
+	})
+	err := g.heartbeatLoop(fetchErrCh, s)
+	g.cfg.hooks.each(func(h Hook) {
+		if h, ok := h.(HookGroupHeartbeatLoopEnd); ok {
+			h.OnGroupHeartbeatLoopEnd(g.cfg.group, memberID, generation, err)
+		}
+	})
+	return err
+}
+
+func (g *groupConsumer) heartbeatLoop(fetchErrCh <-chan error, s *assignRevokeSession) error {
+	ticker := g.cfg.clock.NewTicker(g.cfg.heartbeatInterval)
 	defer ticker.Stop()
 
 	// We issue one heartbeat quickly if we are cooperative because
@@ -724,12 +1241,13 @@ func (g *groupConsumer) heartbeat(fetchErrCh <-chan error, s *assignRevokeSessio
 	// detect that in 500ms rather than 3s.
 	var cooperativeFastCheck <-chan time.Time
 	if g.cooperative {
-		cooperativeFastCheck = time.After(500 * time.Millisecond)
+		cooperativeFastCheck = g.cfg.clock.After(500 * time.Millisecond)
 	}
 
 	var metadone, revoked <-chan struct{}
 	var heartbeat, didMetadone, didRevoke bool
 	var lastErr error
+	var why RejoinReason
 
 	ctxCh := g.ctx.Done()
 
@@ -740,14 +1258,14 @@ func (g *groupConsumer) heartbeat(fetchErrCh <-chan error, s *assignRevokeSessio
 		select {
 		case <-cooperativeFastCheck:
 			heartbeat = true
-		case <-ticker.C:
+		case <-ticker.C():
 			heartbeat = true
 		case force = <-g.heartbeatForceCh:
 			heartbeat = true
-		case why := <-g.rejoinCh:
+		case why = <-g.rejoinCh:
 			// If a metadata update changes our subscription,
 			// we just pretend we are rebalancing.
-			g.cfg.logger.Log(LogLevelInfo, "forced rejoin quitting heartbeat loop", "why", why)
+			g.cfg.logger.Log(LogLevelInfo, "forced rejoin quitting heartbeat loop", "group", g.cfg.group, "why", why)
 			err = kerr.RebalanceInProgress
 		case err = <-fetchErrCh:
 			fetchErrCh = nil
@@ -797,6 +1315,13 @@ func (g *groupConsumer) heartbeat(fetchErrCh <-chan error, s *assignRevokeSessio
 		}
 
 		if lastErr == nil {
+			g.setUnstable()
+			if why == RejoinReasonNone && err == kerr.RebalanceInProgress {
+				why = RejoinReasonGroupRebalancing
+			}
+			if why != RejoinReasonNone {
+				g.setRejoinReason(why)
+			}
 			g.cfg.logger.Log(LogLevelInfo, "heartbeat errored", "group", g.cfg.group, "err", err)
 		} else {
 			g.cfg.logger.Log(LogLevelInfo, "heartbeat errored again while waiting for user revoke to finish", "group", g.cfg.group, "err", err)
@@ -804,10 +1329,10 @@ func (g *groupConsumer) heartbeat(fetchErrCh <-chan error, s *assignRevokeSessio
 
 		// Since we errored, we must revoke.
 		if !didRevoke && revoked == nil {
-			// If our error is not from rebalancing, then we
-			// encountered IllegalGeneration or UnknownMemberID or
-			// our context closed all of which are unexpected and
-			// unrecoverable.
+			// If our error is not from rebalancing nor from our
+			// context closing, then we encountered
+			// IllegalGeneration or UnknownMemberID, which are
+			// unexpected and unrecoverable.
 			//
 			// We return early rather than revoking and updating
 			// metadata; the groupConsumer's manage function will
@@ -816,7 +1341,7 @@ func (g *groupConsumer) heartbeat(fetchErrCh <-chan error, s *assignRevokeSessio
 			// setupAssignedAndHeartbeat still waits for onAssigned
 			// to be done so that we avoid calling onLost
 			// concurrently.
-			if err != kerr.RebalanceInProgress && revoked == nil {
+			if err != kerr.RebalanceInProgress && err != context.Canceled {
 				return err
 			}
 
@@ -825,12 +1350,25 @@ func (g *groupConsumer) heartbeat(fetchErrCh <-chan error, s *assignRevokeSessio
 			// partitions we no longer want to consume.
 			//
 			// If the err is context.Canceled, the group is being
-			// left and we revoke everything.
-			revoked = s.revoke(g, err == context.Canceled)
+			// left and we revoke everything. We also revoke
+			// everything if the user requested a full Rejoin.
+			all := revokeNotAll
+			if g.revokeAll.get() {
+				all = revokeAllRejoining
+			}
+			if err == context.Canceled {
+				all = revokeAllLeaving
+			}
+			g.revokeAll.set(false)
+			revoked = s.revoke(g, all)
 		}
 		// Since we errored, while waiting for the revoke to finish, we
 		// update our metadata. A leader may have re-joined with new
-		// metadata, and we want the update.
+		// metadata, and we want the update. If we are leaving, there
+		// is no reason to update.
+		if !didMetadone && metadone == nil && err == context.Canceled {
+			didMetadone = true
+		}
 		if !didMetadone && metadone == nil {
 			waited := make(chan struct{})
 			metadone = waited
@@ -861,72 +1399,370 @@ func (g *groupConsumer) heartbeat(fetchErrCh <-chan error, s *assignRevokeSessio
 // rebalance and will instead reply to the member with its current assignment.
 func (cl *Client) ForceRebalance() {
 	if g := cl.consumer.g; g != nil {
-		g.rejoin("rejoin from ForceRebalance")
+		g.rejoin(RejoinReasonForceRebalance)
 	}
 }
 
-// rejoin is called after a cooperative member revokes what it lost at the
-// beginning of a session, or if we are leader and detect new partitions to
-// consume.
-func (g *groupConsumer) rejoin(why string) {
-	select {
-	case g.rejoinCh <- why:
-	default:
+// Rejoin revokes all assigned partitions and then rejoins the group,
+// re-acquiring partitions from scratch. For eager consumers, this is the same
+// as a normal rejoin. For cooperative consumers, rather than only revoking
+// partitions that are lost in the next rebalance, this revokes everything
+// (as an eager consumer would) before rejoining.
+//
+// This is heavier than a normal cooperative rejoin: consuming stops for all
+// partitions, OnPartitionsRevoked is called with everything, and the group
+// rebalances without this member owning anything. This is useful to reset
+// internal state after a major change.
+func (cl *Client) Rejoin() {
+	if g := cl.consumer.g; g != nil {
+		g.revokeAll.set(true)
+		g.rejoin(RejoinReasonRejoin)
 	}
 }
 
-// Joins and then syncs, issuing the two slow requests in goroutines to allow
-// for group cancelation to return early.
-func (g *groupConsumer) joinAndSync() error {
-	g.cfg.logger.Log(LogLevelInfo, "joining group", "group", g.cfg.group)
-	g.leader.set(false)
+// RejoinReason is why a group member rejoined the group.
+type RejoinReason int8
 
-start:
-	select {
-	case <-g.rejoinCh: // drain to avoid unnecessary rejoins
+const (
+	// RejoinReasonNone means the group has not been rejoined yet: the
+	// member is joining for the first time.
+	RejoinReasonNone RejoinReason = iota
+	// RejoinReasonGroupRebalancing means Kafka replied to a heartbeat
+	// that the group is rebalancing, i.e., another member triggered the
+	// rebalance.
+	RejoinReasonGroupRebalancing
+	// RejoinReasonSessionError means the prior group session ended with
+	// an error (see HookGroupManageError).
+	RejoinReasonSessionError
+	// RejoinReasonCooperativeRevoke means a cooperative member revoked
+	// partitions it lost and rejoined to release them.
+	RejoinReasonCooperativeRevoke
+	// RejoinReasonForceRebalance means ForceRebalance was called.
+	RejoinReasonForceRebalance
+	// RejoinReasonRejoin means Rejoin was called.
+	RejoinReasonRejoin
+	// RejoinReasonUnwantedAssigned means the member was assigned
+	// partitions of topics it no longer wants.
+	RejoinReasonUnwantedAssigned
+	// RejoinReasonTopicsExcluded means ExcludeGroupTopics was called.
+	RejoinReasonTopicsExcluded
+	// RejoinReasonMetadataChangeFn means RejoinOnMetadataChangeFn
+	// returned true.
+	RejoinReasonMetadataChangeFn
+	// RejoinReasonNewTopics means the member found new topics to
+	// consume, changing its interests.
+	RejoinReasonNewTopics
+	// RejoinReasonNewPartitions means the member, as leader, noticed new
+	// partitions in topics the group is consuming.
+	RejoinReasonNewPartitions
+	// RejoinReasonCommitPartitionErrors means AutoCommitPartitionErrorsFn
+	// requested a rejoin.
+	RejoinReasonCommitPartitionErrors
+)
+
+func (r RejoinReason) String() string {
+	switch r {
+	case RejoinReasonNone:
+		return "none"
+	case RejoinReasonGroupRebalancing:
+		return "group rebalancing"
+	case RejoinReasonSessionError:
+		return "session error"
+	case RejoinReasonCooperativeRevoke:
+		return "cooperative rejoin after revoking what we lost"
+	case RejoinReasonForceRebalance:
+		return "ForceRebalance"
+	case RejoinReasonRejoin:
+		return "Rejoin"
+	case RejoinReasonUnwantedAssigned:
+		return "assigned partitions we no longer want"
+	case RejoinReasonTopicsExcluded:
+		return "topics were excluded from consuming"
+	case RejoinReasonMetadataChangeFn:
+		return "RejoinOnMetadataChangeFn returned true"
+	case RejoinReasonNewTopics:
+		return "more topics to consume, our interests have changed"
+	case RejoinReasonNewPartitions:
+		return "leader noticed some topics have new partitions"
+	case RejoinReasonCommitPartitionErrors:
+		return "AutoCommitPartitionErrorsFn requested a rejoin"
 	default:
+		return "unknown"
 	}
+}
 
-	joinReq := kmsg.NewPtrJoinGroupRequest()
-	joinReq.Group = g.cfg.group
-	joinReq.SessionTimeoutMillis = int32(g.cfg.sessionTimeout.Milliseconds())
-	joinReq.RebalanceTimeoutMillis = int32(g.cfg.rebalanceTimeout.Milliseconds())
-	joinReq.ProtocolType = g.cfg.protocol
-	joinReq.MemberID = g.memberID
-	joinReq.InstanceID = g.cfg.instanceID
-	joinReq.Protocols = g.joinGroupProtocols()
-	var (
-		joinResp *kmsg.JoinGroupResponse
-		err      error
-		joined   = make(chan struct{})
-	)
+// IsCooperative returns whether the client's group consumer is cooperative,
+// and whether the client is consuming as a group at all. The group consumer is
+// cooperative only if every configured balancer is cooperative, and this does
+// not change for the life of the client.
+//
+// Cooperative consumers only revoke the partitions that are moving to other
+// members when rebalancing, whereas eager consumers revoke everything.
+func (cl *Client) IsCooperative() (cooperative, inGroup bool) {
+	g := cl.consumer.g
+	if g == nil {
+		return false, false
+	}
+	return g.cooperative, true
+}
 
-	go func() {
-		defer close(joined)
-		joinResp, err = joinReq.RequestWith(g.ctx, g.cl)
-	}()
+// ConsumedTopics returns the sorted topics that the client is currently
+// consuming. For regex consumers, this is every topic that has been matched by
+// the regular expressions (and not excluded); for group consumers, these are
+// the topics the member is interested in, not necessarily the topics it is
+// assigned. This returns nil if the client is not consuming.
+func (cl *Client) ConsumedTopics() []string {
+	c := &cl.consumer
+	var topics []string
+	switch {
+	case c.g != nil:
+		g := c.g
+		g.mu.Lock()
+		topics = make([]string, 0, len(g.using))
+		for topic := range g.using {
+			topics = append(topics, topic)
+		}
+		g.mu.Unlock()
+	case c.d != nil:
+		c.mu.Lock()
+		topics = make([]string, 0, len(c.d.using))
+		for topic := range c.d.using {
+			topics = append(topics, topic)
+		}
+		c.mu.Unlock()
+	default:
+		return nil
+	}
+	sort.Strings(topics)
+	return topics
+}
 
-	select {
-	case <-joined:
-	case <-g.ctx.Done():
-		return g.ctx.Err() // group killed
+// LastBalancePlan returns the full assignment this member decided for the
+// group the last time it balanced the group as leader, mapping each member ID
+// to the topics and partitions assigned to it. Members that were assigned
+// nothing are included with no topics. This returns nil if the member last
+// joined the group as a follower (or the client is not consuming as a group).
+//
+// This can be used to debug unbalanced groups, e.g. why a member was assigned
+// nothing. The returned map is a copy and can be modified.
+func (cl *Client) LastBalancePlan() map[string]map[string][]int32 {
+	g := cl.consumer.g
+	if g == nil {
+		return nil
 	}
-	if err != nil {
-		return err
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.lastBalancePlan == nil {
+		return nil
 	}
+	plan := make(map[string]map[string][]int32, len(g.lastBalancePlan))
+	for member, topics := range g.lastBalancePlan {
+		dup := make(map[string][]int32, len(topics))
+		for topic, partitions := range topics {
+			dup[topic] = append([]int32(nil), partitions...)
+		}
+		plan[member] = dup
+	}
+	return plan
+}
 
-	restart, protocol, plan, err := g.handleJoinResp(joinResp)
-	if restart {
-		goto start
+// LastRejoinReason returns why the group member most recently rejoined the
+// group, or RejoinReasonNone if the member has not rejoined (or the client is
+// not consuming as a group). This can be used to debug groups that are
+// constantly rebalancing.
+func (cl *Client) LastRejoinReason() RejoinReason {
+	g := cl.consumer.g
+	if g == nil {
+		return RejoinReasonNone
 	}
-	if err != nil {
-		g.cfg.logger.Log(LogLevelWarn, "join group failed", "group", g.cfg.group, "err", err)
-		return err
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lastRejoinReason
+}
+
+// OverrideNextRebalanceTimeout sets a one-shot rebalance timeout to use for
+// the next join of the group, after which the timeout reverts to the
+// configured RebalanceTimeout. This is useful ahead of a planned rebalance
+// where revoking is expected to be slower than usual, such as during a deploy.
+// The override applies to every JoinGroup request (including retries) issued
+// for that one rebalance. Calling this again before the next join replaces
+// the prior override, and a timeout of zero clears it.
+//
+// It is invalid to use a timeout less than 100ms; such timeouts are ignored.
+// This function does nothing if the client is not consuming as a group.
+func (cl *Client) OverrideNextRebalanceTimeout(timeout time.Duration) {
+	g := cl.consumer.g
+	if g == nil || timeout != 0 && timeout < 100*time.Millisecond {
+		return
 	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.nextRebalanceTimeout = timeout
+}
 
-	syncReq := kmsg.NewPtrSyncGroupRequest()
-	syncReq.Group = g.cfg.group
-	syncReq.Generation = g.generation
+// RebalanceTimeout returns the rebalance timeout currently in effect for the
+// group: the timeout sent in the most recent join, which may be a one-shot
+// OverrideNextRebalanceTimeout, or the configured RebalanceTimeout if the
+// member has not yet joined. This returns 0 if the client is not consuming as
+// a group.
+func (cl *Client) RebalanceTimeout() time.Duration {
+	g := cl.consumer.g
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.effectiveRebalanceTimeout()
+}
+
+// effectiveRebalanceTimeout returns the rebalance timeout used in the most
+// recent join, falling back to the configured timeout. This must be called
+// with g.mu held.
+func (g *groupConsumer) effectiveRebalanceTimeout() time.Duration {
+	if g.rebalanceTimeout > 0 {
+		return g.rebalanceTimeout
+	}
+	return g.cfg.rebalanceTimeout
+}
+
+// UnstableOffsetCommitWait returns the total time this client has spent
+// waiting to retry fetching committed offsets because Kafka replied with
+// UNSTABLE_OFFSET_COMMIT, meaning a transaction committing to the group's
+// partitions was pending. This returns 0 if the client is not consuming as a
+// group.
+//
+// While waiting, assigned partitions are not consumed. A steadily increasing
+// wait means the consumer is blocked on a hung transaction; see
+// HookGroupUnstableOffsetFetch to be notified of each retry.
+func (cl *Client) UnstableOffsetCommitWait() time.Duration {
+	g := cl.consumer.g
+	if g == nil {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&g.unstableWait))
+}
+
+func (g *groupConsumer) setRejoinReason(why RejoinReason) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lastRejoinReason = why
+}
+
+// SetRequireStable changes whether the group consumer requires stable offsets
+// when fetching offsets after joining the group (see
+// RequireStableFetchOffsets). This takes effect for the next offset fetch.
+//
+// Disabling this can be used to bypass a stuck transaction that is blocking
+// offset fetches, but doing so loses KIP-447 protection: a member may fetch
+// offsets before a pending transactional commit finishes, and thus reprocess
+// records that the transaction already committed. When consuming in a
+// GroupTransactSession, disabling this also reverts to the short sleep that
+// End uses to reduce (but not eliminate) duplicates. This also applies to
+// FetchOffsetsForGroups and ListGroupOffsets. This does nothing if the client
+// is not consuming as a group.
+func (cl *Client) SetRequireStable(require bool) {
+	if g := cl.consumer.g; g != nil {
+		g.requireStable.set(require)
+		g.cfg.logger.Log(LogLevelInfo, "set require stable fetch offsets", "group", g.cfg.group, "require_stable", require)
+	}
+}
+
+// requireStable returns whether offset fetches should require stable offsets:
+// the group's current setting if consuming as a group, otherwise
+// RequireStableFetchOffsets.
+func (cl *Client) requireStable() bool {
+	if g := cl.consumer.g; g != nil {
+		return g.requireStable.get()
+	}
+	return cl.cfg.requireStable
+}
+
+// rejoin is called after a cooperative member revokes what it lost at the
+// beginning of a session, or if we are leader and detect new partitions to
+// consume.
+func (g *groupConsumer) rejoin(why RejoinReason) {
+	select {
+	case g.rejoinCh <- why:
+	default:
+	}
+}
+
+// Joins and then syncs, issuing the two slow requests in goroutines to allow
+// for group cancelation to return early.
+func (g *groupConsumer) joinAndSync() error {
+	g.mu.Lock()
+	why := g.lastRejoinReason
+	rebalanceTimeout := g.cfg.rebalanceTimeout
+	if g.nextRebalanceTimeout > 0 {
+		rebalanceTimeout = g.nextRebalanceTimeout
+		g.nextRebalanceTimeout = 0
+	}
+	g.rebalanceTimeout = rebalanceTimeout
+	g.mu.Unlock()
+	g.cfg.logger.Log(LogLevelInfo, "joining group", "group", g.cfg.group, "why", why, "rebalance_timeout", rebalanceTimeout)
+	g.leader.set(false)
+
+	var syncCoordinatorRetries int
+
+	// If configured, we give up on a join & sync that takes too long
+	// (across every restart below); see LeaveOnStuckRebalance.
+	var stuck <-chan time.Time
+	stuckAfter := time.Duration(g.cfg.stuckRebalanceMultiple * float64(rebalanceTimeout))
+	if stuckAfter > 0 {
+		stuck = g.cfg.clock.After(stuckAfter)
+	}
+
+start:
+	select {
+	case <-g.rejoinCh: // drain to avoid unnecessary rejoins
+	default:
+	}
+
+	joinReq := kmsg.NewPtrJoinGroupRequest()
+	joinReq.Group = g.cfg.group
+	joinReq.SessionTimeoutMillis = int32(g.cfg.sessionTimeout.Milliseconds())
+	joinReq.RebalanceTimeoutMillis = int32(rebalanceTimeout.Milliseconds())
+	joinReq.ProtocolType = g.cfg.protocol
+	joinReq.MemberID = g.memberID
+	joinReq.InstanceID = g.cfg.instanceID
+	joinReq.Protocols = g.joinGroupProtocols()
+	if g.cfg.adjustJoinGroupRequest != nil {
+		g.cfg.adjustJoinGroupRequest(joinReq)
+	}
+	var (
+		joinResp *kmsg.JoinGroupResponse
+		err      error
+		joined   = make(chan struct{})
+	)
+
+	go func() {
+		defer close(joined)
+		joinResp, err = joinReq.RequestWith(g.ctx, g.cl)
+	}()
+
+	select {
+	case <-joined:
+	case <-g.ctx.Done():
+		return g.ctx.Err() // group killed
+	case <-stuck:
+		return g.leaveStuckRebalance(stuckAfter)
+	}
+	if err != nil {
+		return err
+	}
+
+	restart, protocol, plan, err := g.handleJoinResp(joinResp)
+	if restart {
+		goto start
+	}
+	if err != nil {
+		g.cfg.logger.Log(LogLevelWarn, "join group failed", "group", g.cfg.group, "err", err)
+		return err
+	}
+
+	syncReq := kmsg.NewPtrSyncGroupRequest()
+	syncReq.Group = g.cfg.group
+	syncReq.Generation = g.generation
 	syncReq.MemberID = g.memberID
 	syncReq.InstanceID = g.cfg.instanceID
 	syncReq.ProtocolType = &g.cfg.protocol
@@ -947,16 +1783,38 @@ start:
 	case <-synced:
 	case <-g.ctx.Done():
 		return g.ctx.Err()
+	case <-stuck:
+		return g.leaveStuckRebalance(stuckAfter)
 	}
-	if err != nil {
-		return err
+	if err == nil {
+		err = g.handleSyncResp(protocol, syncResp)
 	}
-
-	if err = g.handleSyncResp(protocol, syncResp); err != nil {
+	if err != nil {
 		if err == kerr.RebalanceInProgress {
 			g.cfg.logger.Log(LogLevelInfo, "sync failed with RebalanceInProgress, rejoining", "group", g.cfg.group)
 			goto start
 		}
+
+		// If the coordinator moved or is loading, the request
+		// retry logic has already deleted our stale coordinator.
+		// We briefly back off and rejoin rather than returning,
+		// which would invalidate our partitions and back off in
+		// the manage loop.
+		if isSyncCoordinatorErr(err) && syncCoordinatorRetries < maxSyncCoordinatorRetries {
+			syncCoordinatorRetries++
+			backoff := g.rebalanceBackoff(syncCoordinatorRetries)
+			g.cfg.logger.Log(LogLevelInfo, "sync failed with a transient coordinator error, rejoining after backoff",
+				"group", g.cfg.group,
+				"err", err,
+				"backoff", backoff,
+			)
+			select {
+			case <-g.ctx.Done():
+				return g.ctx.Err()
+			case <-g.cfg.clock.After(backoff):
+			}
+			goto start
+		}
 		g.cfg.logger.Log(LogLevelWarn, "sync group failed", "group", g.cfg.group, "err", err)
 		return err
 	}
@@ -964,6 +1822,89 @@ start:
 	return nil
 }
 
+// maxSyncCoordinatorRetries is how many times joinAndSync rejoins on a sync
+// failing with a transient coordinator error before returning the error.
+const maxSyncCoordinatorRetries = 3
+
+// isSyncCoordinatorErr returns whether a sync error indicates that our
+// coordinator moved or is not yet ready, in which case we can rejoin.
+func isSyncCoordinatorErr(err error) bool {
+	switch err {
+	case kerr.NotCoordinator,
+		kerr.CoordinatorLoadInProgress,
+		kerr.CoordinatorNotAvailable:
+		return true
+	}
+	return false
+}
+
+// describeMemberCount issues a DescribeGroups request to learn how many
+// members are in the group, caching the count for the given generation if the
+// group is still on that generation and the group is stable. This closes done
+// once finished.
+func (g *groupConsumer) describeMemberCount(generation int32, done chan struct{}) {
+	defer func() {
+		g.mu.Lock()
+		g.memberCountDone = nil
+		g.mu.Unlock()
+		close(done)
+	}()
+
+	req := kmsg.NewPtrDescribeGroupsRequest()
+	req.Groups = append(req.Groups, g.cfg.group)
+	resp, err := req.RequestWith(g.ctx, g.cl)
+	if err == nil && len(resp.Groups) != 1 {
+		err = fmt.Errorf("describe groups response unexpectedly has %d groups", len(resp.Groups))
+	}
+	if err == nil {
+		err = kerr.ErrorForCode(resp.Groups[0].ErrorCode)
+	}
+	if err != nil {
+		g.cfg.logger.Log(LogLevelInfo, "unable to describe group to learn the group member count", "group", g.cfg.group, "err", err)
+		return
+	}
+	described := &resp.Groups[0]
+	if described.State != "Stable" {
+		g.cfg.logger.Log(LogLevelInfo, "described group is not stable, not caching the group member count", "group", g.cfg.group, "state", described.State)
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.generation == generation {
+		g.memberCount = len(described.Members)
+		g.memberCountGeneration = generation
+	}
+}
+
+// GroupMemberCount returns the number of members in the group as of this
+// member's current generation, and whether the count is known.
+//
+// The group leader learns the count immediately when joining. Followers learn
+// the count from a DescribeGroups request: if the count is not known for the
+// current generation, this issues the request in the background and returns
+// false, and a later call returns the count once the request completes. The
+// count is only cached if the described group is stable, otherwise a later
+// call describes the group again. This returns false if the client is not
+// consuming as a group or if the count is not yet known for the current
+// generation.
+func (cl *Client) GroupMemberCount() (int, bool) {
+	g := cl.consumer.g
+	if g == nil {
+		return 0, false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.memberCountGeneration >= 0 && g.memberCountGeneration == g.generation {
+		return g.memberCount, true
+	}
+	if g.memberCountDone == nil && !g.dying && g.memberID != "" {
+		g.memberCountDone = make(chan struct{})
+		go g.describeMemberCount(g.generation, g.memberCountDone)
+	}
+	return 0, false
+}
+
 func (g *groupConsumer) handleJoinResp(resp *kmsg.JoinGroupResponse) (restart bool, protocol string, plan []kmsg.SyncGroupRequestGroupAssignment, err error) {
 	if err = kerr.ErrorForCode(resp.ErrorCode); err != nil {
 		switch err {
@@ -987,9 +1928,18 @@ func (g *groupConsumer) handleJoinResp(resp *kmsg.JoinGroupResponse) (restart bo
 	// race with this function. We need to lock setting these two fields.
 	g.mu.Lock()
 	g.memberID = resp.MemberID
+	priorGeneration := g.generation
 	g.generation = resp.Generation
 	g.mu.Unlock()
 
+	if resp.Generation != priorGeneration {
+		g.cfg.hooks.each(func(h Hook) {
+			if h, ok := h.(HookGroupGenerationChange); ok {
+				h.OnGroupGenerationChange(g.cfg.group, resp.MemberID, resp.Generation)
+			}
+		})
+	}
+
 	if resp.Protocol != nil {
 		protocol = *resp.Protocol
 	}
@@ -997,6 +1947,10 @@ func (g *groupConsumer) handleJoinResp(resp *kmsg.JoinGroupResponse) (restart bo
 	leader := resp.LeaderID == resp.MemberID
 	if leader {
 		g.leader.set(true)
+		g.mu.Lock()
+		g.memberCount = len(resp.Members)
+		g.memberCountGeneration = resp.Generation
+		g.mu.Unlock()
 		g.cfg.logger.Log(LogLevelInfo, "joined, balancing group",
 			"group", g.cfg.group,
 			"member_id", g.memberID,
@@ -1012,6 +1966,9 @@ func (g *groupConsumer) handleJoinResp(resp *kmsg.JoinGroupResponse) (restart bo
 		}
 
 	} else {
+		g.mu.Lock()
+		g.lastBalancePlan = nil
+		g.mu.Unlock()
 		g.cfg.logger.Log(LogLevelInfo, "joined",
 			"group", g.cfg.group,
 			"member_id", g.memberID,
@@ -1041,6 +1998,34 @@ func (g *groupConsumer) handleSyncResp(protocol string, resp *kmsg.SyncGroupResp
 
 	g.cfg.logger.Log(LogLevelInfo, "synced", "group", g.cfg.group, "assigned", tpsFmt(assigned))
 
+	if sb, ok := b.(GroupStandbyBalancer); ok {
+		standby, err := sb.ParseStandbySyncAssignment(resp.MemberAssignment)
+		if err != nil {
+			g.cfg.logger.Log(LogLevelError, "standby sync assignment parse failed", "group", g.cfg.group, "err", err)
+			return err
+		}
+		if standby != nil {
+			g.cfg.logger.Log(LogLevelInfo, "synced standby", "group", g.cfg.group, "standby", tpsFmt(standby))
+			g.cfg.hooks.each(func(h Hook) {
+				if h, ok := h.(HookGroupStandbyAssigned); ok {
+					h.OnGroupStandbyAssigned(g.cfg.group, standby)
+				}
+			})
+		}
+	}
+
+	if max := g.cfg.maxAssignedPartitions; max > 0 {
+		var dropped map[string][]int32
+		if assigned, dropped = trimAssigned(assigned, max); len(dropped) > 0 {
+			g.cfg.logger.Log(LogLevelWarn, "assigned more partitions than MaxAssignedPartitions allows, dropping extra partitions",
+				"group", g.cfg.group,
+				"max", max,
+				"kept", tpsFmt(assigned),
+				"dropped", tpsFmt(dropped),
+			)
+		}
+	}
+
 	// Past this point, we will fall into the setupAssigned prerevoke code,
 	// meaning for cooperative, we will revoke what we need to.
 	if g.cooperative {
@@ -1050,6 +2035,44 @@ func (g *groupConsumer) handleSyncResp(protocol string, resp *kmsg.SyncGroupResp
 	return nil
 }
 
+// trimAssigned returns the assignment trimmed to at most max partitions, as
+// well as what was trimmed. We keep partitions in topic then partition order
+// so that trimming is deterministic.
+func trimAssigned(assigned map[string][]int32, max int) (kept, dropped map[string][]int32) {
+	var total int
+	for _, partitions := range assigned {
+		total += len(partitions)
+	}
+	if total <= max {
+		return assigned, nil
+	}
+
+	topics := make([]string, 0, len(assigned))
+	for topic := range assigned {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	kept = make(map[string][]int32, len(assigned))
+	dropped = make(map[string][]int32)
+	for _, topic := range topics {
+		partitions := append([]int32(nil), assigned[topic]...)
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+		keep := len(partitions)
+		if keep > max {
+			keep = max
+		}
+		if keep > 0 {
+			kept[topic] = partitions[:keep]
+		}
+		if keep < len(partitions) {
+			dropped[topic] = partitions[keep:]
+		}
+		max -= keep
+	}
+	return kept, dropped
+}
+
 func (g *groupConsumer) joinGroupProtocols() []kmsg.JoinGroupRequestProtocol {
 	g.mu.Lock()
 
@@ -1070,16 +2093,53 @@ func (g *groupConsumer) joinGroupProtocols() []kmsg.JoinGroupRequestProtocol {
 		sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] }) // same for partitions
 	}
 
+	protos := g.buildJoinGroupProtocols(topics, nowDup, gen)
+	if !g.joinMetadataTooLarge(protos) || !g.cfg.joinMetadataTrimOwned || len(nowDup) == 0 {
+		return protos
+	}
+
+	g.cfg.logger.Log(LogLevelWarn, "join group metadata is too large, trimming owned partitions from the metadata", "group", g.cfg.group)
+	protos = g.buildJoinGroupProtocols(topics, make(map[string][]int32), gen)
+	g.joinMetadataTooLarge(protos)
+	return protos
+}
+
+func (g *groupConsumer) buildJoinGroupProtocols(topics []string, current map[string][]int32, gen int32) []kmsg.JoinGroupRequestProtocol {
 	var protos []kmsg.JoinGroupRequestProtocol
 	for _, balancer := range g.cfg.balancers {
 		proto := kmsg.NewJoinGroupRequestProtocol()
 		proto.Name = balancer.ProtocolName()
-		proto.Metadata = balancer.JoinGroupMetadata(topics, nowDup, gen)
+		proto.Metadata = balancer.JoinGroupMetadata(topics, current, gen)
 		protos = append(protos, proto)
 	}
 	return protos
 }
 
+// joinMetadataTooLarge returns whether the total metadata in protos exceeds
+// JoinGroupMetadataMaxBytes, logging each protocol's size if so.
+func (g *groupConsumer) joinMetadataTooLarge(protos []kmsg.JoinGroupRequestProtocol) bool {
+	max := g.cfg.joinMetadataMaxBytes
+	if max <= 0 {
+		return false
+	}
+	var total int
+	sizes := make(map[string]int, len(protos))
+	for _, proto := range protos {
+		total += len(proto.Metadata)
+		sizes[proto.Name] = len(proto.Metadata)
+	}
+	if total <= int(max) {
+		return false
+	}
+	g.cfg.logger.Log(LogLevelWarn, "join group metadata exceeds JoinGroupMetadataMaxBytes, the broker may fail the group; consider consuming fewer topics, using fewer balancers, or TrimJoinGroupOwnedPartitions",
+		"group", g.cfg.group,
+		"total_bytes", total,
+		"max_bytes", max,
+		"protocol_bytes", sizes,
+	)
+	return true
+}
+
 // If we are cooperatively consuming, we have a potential problem: if fetch
 // offsets is canceled due to an immediate rebalance, when we resume, we will
 // not re-fetch offsets for partitions we were previously assigned and are
@@ -1091,9 +2151,9 @@ func (g *groupConsumer) joinGroupProtocols() []kmsg.JoinGroupRequestProtocol {
 // Now, if fetching returns early due to an error, when we rejoin and re-fetch,
 // we will resume fetching what we were previously:
 //
-//   * first we remove what was lost
-//   * then we add anything new
-//   * then we translate our total set into the "added" list to be fetched on return
+//   - first we remove what was lost
+//   - then we add anything new
+//   - then we translate our total set into the "added" list to be fetched on return
 //
 // Any time a group is completely lost, the manage loop clears fetching. When
 // cooperative consuming, a hard error is basically losing the entire state and
@@ -1159,13 +2219,117 @@ func (g *groupConsumer) fetchOffsets(ctx context.Context, added, lost map[string
 		}()
 	}
 
+	var offsets map[string]map[int32]Offset
+	if g.cfg.externalOffsets != nil {
+		offsets, err = g.fetchExternalOffsets(ctx, added)
+	} else {
+		offsets, err = g.fetchCommittedOffsets(ctx, added)
+	}
+	if err != nil {
+		return err
+	}
+
+	groupTopics := g.tps.load()
+	var unrequested []string
+	for fetchedTopic := range offsets {
+		if !groupTopics.hasTopic(fetchedTopic) {
+			if g.cfg.failUnrequestedTopics {
+				unrequested = append(unrequested, fetchedTopic)
+				continue
+			}
+			delete(offsets, fetchedTopic)
+			g.cfg.logger.Log(LogLevelWarn, "member was assigned topic that we did not ask for in ConsumeTopics! skipping assigning this topic!", "group", g.cfg.group, "topic", fetchedTopic)
+		}
+	}
+	if len(unrequested) > 0 {
+		sort.Strings(unrequested)
+		return &ErrUnrequestedTopicAssigned{Group: g.cfg.group, Topics: unrequested}
+	}
+
+	// With regex consuming, we know of topics that we do not want, and
+	// our subscription may have changed since the leader balanced. We do
+	// not assign any partition of a topic we are not using; for
+	// cooperative consumers, we rejoin to release the partitions at the
+	// end of this session (see revoke).
+	if unwanted := g.unwantedOffsets(offsets); len(unwanted) > 0 {
+		g.cfg.logger.Log(LogLevelWarn, "member was assigned partitions that we no longer want, skipping assigning these partitions", "group", g.cfg.group, "unwanted", tpsFmt(unwanted))
+		if g.cooperative {
+			g.rejoin(RejoinReasonUnwantedAssigned)
+		}
+	}
+	if g.cfg.adjustOffsetsBeforeAssign != nil {
+		if offsets, err = g.cfg.adjustOffsetsBeforeAssign(ctx, offsets); err != nil {
+			return err
+		}
+	}
+
+	// If this is our first fetch and we are skipping the backlog, we
+	// consume from the end. We still track the committed offsets below.
+	assignOffsets := offsets
+	if g.cfg.latestOnFirstJoin && !g.fetchedOffsets {
+		g.cfg.logger.Log(LogLevelInfo, "ignoring committed offsets for the first fetch of offsets, consuming from the end", "group", g.cfg.group)
+		assignOffsets = make(map[string]map[int32]Offset, len(offsets))
+		for topic, partitions := range offsets {
+			latest := make(map[int32]Offset, len(partitions))
+			for partition := range partitions {
+				latest[partition] = NewOffset().AtEnd()
+			}
+			assignOffsets[topic] = latest
+		}
+	}
+	g.fetchedOffsets = true
+
+	// Lock for assign and then updating uncommitted.
+	g.c.mu.Lock()
+	defer g.c.mu.Unlock()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Eager: we already invalidated everything; nothing to re-invalidate.
+	// Cooperative: assign without invalidating what we are consuming.
+	g.c.assignPartitions(assignOffsets, assignWithoutInvalidating, g.tps, fmt.Sprintf("newly fetched offsets for group %s", g.cfg.group))
+
+	// We need to update the uncommited map so that SetOffsets(Committed)
+	// does not rewind before the committed offsets we just fetched.
+	if g.uncommitted == nil {
+		g.uncommitted = make(uncommitted, 10)
+	}
+	for topic, partitions := range offsets {
+		topicUncommitted := g.uncommitted[topic]
+		if topicUncommitted == nil {
+			topicUncommitted = make(map[int32]uncommit, 20)
+			g.uncommitted[topic] = topicUncommitted
+		}
+		for partition, offset := range partitions {
+			if offset.at < 0 {
+				continue // not yet committed
+			}
+			committed := EpochOffset{
+				Epoch:  offset.epoch,
+				Offset: offset.at,
+			}
+			topicUncommitted[partition] = uncommit{
+				dirty:     committed,
+				head:      committed,
+				committed: committed,
+			}
+		}
+	}
+	return nil
+}
+
+// fetchCommittedOffsets issues an OffsetFetch for all added partitions and
+// returns the offsets to consume from.
+func (g *groupConsumer) fetchCommittedOffsets(ctx context.Context, added map[string][]int32) (_ map[string]map[int32]Offset, err error) {
+	var unstableTries int
+
 	// Our client maps the v0 to v7 format to v8+ when sharding this
 	// request, if we are only requesting one group, as well as maps the
 	// response back, so we do not need to worry about v8+ here.
 start:
 	req := kmsg.NewPtrOffsetFetchRequest()
 	req.Group = g.cfg.group
-	req.RequireStable = g.cfg.requireStable
+	req.RequireStable = g.requireStable.get()
 	for topic, partitions := range added {
 		reqTopic := kmsg.NewOffsetFetchRequestTopic()
 		reqTopic.Topic = topic
@@ -1175,20 +2339,36 @@ start:
 
 	var resp *kmsg.OffsetFetchResponse
 
+	fetchStart := time.Now()
+	fetchHook := func(err error) {
+		took := time.Since(fetchStart)
+		g.cfg.hooks.each(func(h Hook) {
+			if h, ok := h.(HookGroupOffsetFetch); ok {
+				var partitions int
+				for _, ps := range added {
+					partitions += len(ps)
+				}
+				h.OnGroupOffsetFetch(g.cfg.group, partitions, took, err)
+			}
+		})
+	}
+
 	fetchDone := make(chan struct{})
 	go func() {
 		defer close(fetchDone)
-		resp, err = req.RequestWith(ctx, g.cl)
+		resp, err = req.RequestWith(ctx, g.offsetsClient())
 	}()
 	select {
 	case <-fetchDone:
 	case <-ctx.Done():
+		fetchHook(ctx.Err())
 		g.cfg.logger.Log(LogLevelInfo, "fetch offsets failed due to context cancelation", "group", g.cfg.group)
-		return ctx.Err()
+		return nil, ctx.Err()
 	}
+	fetchHook(err)
 	if err != nil {
 		g.cfg.logger.Log(LogLevelError, "fetch offsets failed with non-retriable error", "group", g.cfg.group, "err", err)
-		return err
+		return nil, err
 	}
 
 	// Even if a leader epoch is returned, if brokers do not support
@@ -1204,16 +2384,32 @@ start:
 			if err = kerr.ErrorForCode(rPartition.ErrorCode); err != nil {
 				// KIP-447: Unstable offset commit means there is a
 				// pending transaction that should be committing soon.
-				// We sleep for 1s and retry fetching offsets.
+				// We sleep (by default, for 1s) and retry fetching
+				// offsets.
 				if err == kerr.UnstableOffsetCommit {
-					g.cfg.logger.Log(LogLevelInfo, "fetch offsets failed with UnstableOffsetCommit, waiting 1s and retrying",
+					unstableTries++
+					backoff := time.Second
+					if g.cfg.offsetFetchBackoff != nil {
+						backoff = g.cfg.offsetFetchBackoff(unstableTries)
+					}
+					g.cfg.logger.Log(LogLevelInfo, "fetch offsets failed with UnstableOffsetCommit, waiting and retrying",
 						"group", g.cfg.group,
 						"topic", rTopic.Topic,
 						"partition", rPartition.Partition,
+						"attempt", unstableTries,
+						"backoff", backoff,
 					)
+					g.cfg.hooks.each(func(h Hook) {
+						if h, ok := h.(HookGroupUnstableOffsetFetch); ok {
+							h.OnGroupUnstableOffsetFetch(g.cfg.group, rTopic.Topic, rPartition.Partition, unstableTries, backoff)
+						}
+					})
+					waitStart := g.cfg.clock.Now()
 					select {
 					case <-ctx.Done():
-					case <-time.After(time.Second):
+						atomic.AddInt64(&g.unstableWait, int64(g.cfg.clock.Now().Sub(waitStart)))
+					case <-g.cfg.clock.After(backoff):
+						atomic.AddInt64(&g.unstableWait, int64(g.cfg.clock.Now().Sub(waitStart)))
 						goto start
 					}
 				}
@@ -1223,7 +2419,7 @@ start:
 					"partition", rPartition.Partition,
 					"err", err,
 				)
-				return err
+				return nil, err
 			}
 			offset := Offset{
 				at:    rPartition.Offset,
@@ -1238,56 +2434,141 @@ start:
 			topicOffsets[rPartition.Partition] = offset
 		}
 	}
+	return offsets, nil
+}
 
-	groupTopics := g.tps.load()
-	for fetchedTopic := range offsets {
-		if !groupTopics.hasTopic(fetchedTopic) {
-			delete(offsets, fetchedTopic)
-			g.cfg.logger.Log(LogLevelWarn, "member was assigned topic that we did not ask for in ConsumeTopics! skipping assigning this topic!", "group", g.cfg.group, "topic", fetchedTopic)
-		}
+// fetchExternalOffsets calls the user's external offsets function rather than
+// issuing an OffsetFetch. Any added partition that the function does not return
+// an offset for uses the reset offset.
+func (g *groupConsumer) fetchExternalOffsets(ctx context.Context, added map[string][]int32) (map[string]map[int32]Offset, error) {
+	external, err := g.cfg.externalOffsets(ctx, added)
+	if err != nil {
+		g.cfg.logger.Log(LogLevelError, "external offsets function failed", "group", g.cfg.group, "err", err)
+		return nil, err
 	}
-	if g.cfg.adjustOffsetsBeforeAssign != nil {
-		if offsets, err = g.cfg.adjustOffsetsBeforeAssign(ctx, offsets); err != nil {
-			return err
+	offsets := make(map[string]map[int32]Offset, len(added))
+	for topic, partitions := range added {
+		topicOffsets := make(map[int32]Offset, len(partitions))
+		offsets[topic] = topicOffsets
+		for _, partition := range partitions {
+			eo, exists := external[topic][partition]
+			if !exists || eo.Offset < 0 {
+				topicOffsets[partition] = g.cfg.resetOffset
+				continue
+			}
+			topicOffsets[partition] = Offset{
+				at:    eo.Offset,
+				epoch: eo.Epoch,
+			}
 		}
 	}
+	return offsets, nil
+}
 
-	// Lock for assign and then updating uncommitted.
-	g.c.mu.Lock()
-	defer g.c.mu.Unlock()
+// unwantedOffsets deletes and returns partitions from offsets for topics that
+// we are not using.
+func (g *groupConsumer) unwantedOffsets(offsets map[string]map[int32]Offset) map[string][]int32 {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	// Eager: we already invalidated everything; nothing to re-invalidate.
-	// Cooperative: assign without invalidating what we are consuming.
-	g.c.assignPartitions(offsets, assignWithoutInvalidating, g.tps, fmt.Sprintf("newly fetched offsets for group %s", g.cfg.group))
-
-	// We need to update the uncommited map so that SetOffsets(Committed)
-	// does not rewind before the committed offsets we just fetched.
-	if g.uncommitted == nil {
-		g.uncommitted = make(uncommitted, 10)
-	}
+	var unwanted map[string][]int32
 	for topic, partitions := range offsets {
-		topicUncommitted := g.uncommitted[topic]
-		if topicUncommitted == nil {
-			topicUncommitted = make(map[int32]uncommit, 20)
-			g.uncommitted[topic] = topicUncommitted
+		if _, using := g.using[topic]; using {
+			continue
 		}
-		for partition, offset := range partitions {
-			if offset.at < 0 {
-				continue // not yet committed
-			}
-			committed := EpochOffset{
-				Epoch:  offset.epoch,
-				Offset: offset.at,
+		if unwanted == nil {
+			unwanted = make(map[string][]int32)
+		}
+		for partition := range partitions {
+			unwanted[topic] = append(unwanted[topic], partition)
+		}
+		delete(offsets, topic)
+	}
+	return unwanted
+}
+
+// deleteUnwantedAssigned deletes and returns partitions from nowAssigned for
+// topics that we are not using.
+func (g *groupConsumer) deleteUnwantedAssigned() map[string][]int32 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// nowAssigned may be shared with what was passed to onAssigned, so
+	// rather than deleting from it, we replace it.
+	var unwanted map[string][]int32
+	for topic, partitions := range g.nowAssigned {
+		if _, using := g.using[topic]; !using {
+			if unwanted == nil {
+				unwanted = make(map[string][]int32)
 			}
-			topicUncommitted[partition] = uncommit{
-				dirty:     committed,
-				head:      committed,
-				committed: committed,
+			unwanted[topic] = partitions
+		}
+	}
+	if unwanted == nil {
+		return nil
+	}
+	wanted := make(map[string][]int32, len(g.nowAssigned))
+	for topic, partitions := range g.nowAssigned {
+		if _, isUnwanted := unwanted[topic]; !isUnwanted {
+			wanted[topic] = partitions
+		}
+	}
+	g.nowAssigned = wanted
+	return unwanted
+}
+
+// ExcludeGroupTopics stops consuming the given topics as a group member,
+// even if they match a regex subscription, and rejoins the group if any of
+// the topics were being consumed. This can be used to tighten a subscription
+// at runtime without leaving the group.
+//
+// Eager consumers revoke everything when rejoining, as usual. Cooperative
+// consumers only revoke partitions of the excluded topics (calling
+// OnPartitionsRevoked with just those partitions) before rejoining again to
+// release them, and continue consuming everything else throughout.
+//
+// It is invalid to exclude every topic that the group is consuming; to stop
+// consuming entirely, leave the group. This function does nothing if the
+// client is not consuming as a group.
+func (cl *Client) ExcludeGroupTopics(topics ...string) error {
+	c := &cl.consumer
+	g := c.g
+	if g == nil || len(topics) == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g.mu.Lock()
+	var remaining int
+	for topic := range g.using {
+		remaining++
+		for _, exclude := range topics {
+			if topic == exclude {
+				remaining--
+				break
 			}
 		}
 	}
+	if len(g.using) > 0 && remaining == 0 {
+		g.mu.Unlock()
+		return errExcludeAllGroupTopics
+	}
+	var rejoin bool
+	for _, topic := range topics {
+		g.excluded[topic] = struct{}{}
+		if _, using := g.using[topic]; using {
+			delete(g.using, topic)
+			rejoin = true
+		}
+	}
+	g.mu.Unlock()
+
+	if rejoin {
+		g.cfg.logger.Log(LogLevelInfo, "excluding topics from group consuming", "group", g.cfg.group, "topics", topics)
+		g.rejoin(RejoinReasonTopicsExcluded)
+	}
 	return nil
 }
 
@@ -1295,14 +2576,17 @@ start:
 // We only grab the group mu at the end if we need to.
 //
 // This joins the group if
-//  - the group has never been joined
-//  - new topics are found for consuming (changing this consumer's join metadata)
+//   - the group has never been joined
+//   - new topics are found for consuming (changing this consumer's join metadata)
 //
 // Additionally, if the member is the leader, this rejoins the group if the
 // leader notices new partitions in an existing topic.
 //
 // This does not rejoin if the leader notices a partition is lost, which is
 // finicky.
+//
+// If the user provided a RejoinOnMetadataChangeFn, that function decides
+// whether to rejoin rather than the logic above.
 func (g *groupConsumer) findNewAssignments() {
 	topics := g.tps.load()
 
@@ -1319,6 +2603,9 @@ func (g *groupConsumer) findNewAssignments() {
 	var numNewTopics int
 	toChange := make(map[string]change, len(topics))
 	for topic, topicPartitions := range topics {
+		if _, excluded := g.excluded[topic]; excluded {
+			continue
+		}
 		parts := topicPartitions.load()
 		numPartitions := len(parts.partitions)
 		// If we are already using this topic, add that it changed if
@@ -1364,32 +2651,132 @@ func (g *groupConsumer) findNewAssignments() {
 
 	}
 
-	if len(toChange) == 0 {
+	rejoinFn := g.cfg.rejoinOnMetadataChange
+	if len(toChange) == 0 && rejoinFn == nil {
 		return
 	}
 
 	g.mu.Lock()
-	defer g.mu.Unlock()
 
 	if g.dying {
+		g.mu.Unlock()
 		return
 	}
 
 	wasManaging := len(g.using) != 0
+	if !wasManaging && len(toChange) == 0 {
+		g.mu.Unlock()
+		return
+	}
+
+	var current map[string]int
+	if rejoinFn != nil {
+		current = make(map[string]int, len(g.using))
+		for topic, partitions := range g.using {
+			current[topic] = partitions
+		}
+	}
 	for topic, change := range toChange {
 		g.using[topic] += change.delta
 	}
 
 	if !wasManaging {
+		g.mu.Unlock()
 		go g.manage()
 		return
 	}
 
+	if rejoinFn != nil {
+		proposed := make(map[string]int, len(g.using))
+		for topic, partitions := range g.using {
+			proposed[topic] = partitions
+		}
+		g.mu.Unlock()
+
+		// We call the user function outside of the group lock, but we
+		// are still within the consumer lock (see doOnMetadataUpdate).
+		if rejoinFn(current, proposed) {
+			g.rejoinSubscriptionChanged(RejoinReasonMetadataChangeFn)
+		}
+		return
+	}
+	g.mu.Unlock()
+
 	if numNewTopics > 0 {
-		g.rejoin("rejoining because there are more topics to consume, our interests have changed")
+		g.rejoinSubscriptionChanged(RejoinReasonNewTopics)
 	} else if g.leader.get() {
-		g.rejoin("rejoining because we are the leader and noticed some topics have new partitions")
+		g.rejoinNewPartitions()
+	}
+}
+
+// rejoinSubscriptionChanged rejoins because our subscription changed,
+// debouncing the rejoin if configured.
+func (g *groupConsumer) rejoinSubscriptionChanged(why RejoinReason) {
+	window := g.cfg.subscriptionRejoinWindow
+	if window <= 0 {
+		g.rejoin(why)
+		return
+	}
+
+	g.mu.Lock()
+	g.subscriptionRejoinSeq++
+	seq := g.subscriptionRejoinSeq
+	g.mu.Unlock()
+	g.cfg.logger.Log(LogLevelDebug, "subscription changed, debouncing rejoin", "group", g.cfg.group, "why", why, "window", window)
+
+	go func() {
+		select {
+		case <-g.ctx.Done():
+			return
+		case <-g.cfg.clock.After(window):
+		}
+		g.mu.Lock()
+		latest := seq == g.subscriptionRejoinSeq
+		g.mu.Unlock()
+		if latest {
+			g.rejoin(why)
+		}
+	}()
+}
+
+// rejoinNewPartitions rejoins because we are the leader and noticed some
+// topics have new partitions, delaying or skipping the rejoin if configured.
+func (g *groupConsumer) rejoinNewPartitions() {
+	delay := g.cfg.newPartitionsRejoinDelay
+	if delay == 0 {
+		g.rejoin(RejoinReasonNewPartitions)
+		return
+	}
+	if delay < 0 {
+		g.cfg.logger.Log(LogLevelDebug, "leader noticed new partitions, not rejoining until the next rebalance", "group", g.cfg.group)
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.newPartitionsRejoinPending {
+		return // coalesce into the pending rejoin
 	}
+	g.newPartitionsRejoinPending = true
+	generation := g.generation
+	g.cfg.logger.Log(LogLevelInfo, "leader noticed new partitions, delaying rejoin", "group", g.cfg.group, "delay", delay)
+
+	go func() {
+		select {
+		case <-g.ctx.Done():
+		case <-g.cfg.clock.After(delay):
+		}
+		g.mu.Lock()
+		g.newPartitionsRejoinPending = false
+		rebalanced := g.generation != generation
+		g.mu.Unlock()
+
+		// If the group rebalanced while we waited, the rebalance
+		// already picked up the new partitions.
+		if !rebalanced && g.ctx.Err() == nil {
+			g.rejoin(RejoinReasonNewPartitions)
+		}
+	}()
 }
 
 // uncommit tracks the latest offset polled (+1) and the latest commit.
@@ -1399,6 +2786,10 @@ type uncommit struct {
 	dirty     EpochOffset // if autocommitting, what will move to head on next Poll
 	head      EpochOffset // ready to commit
 	committed EpochOffset // what is committed
+
+	// polled is when the oldest record past committed was polled, or
+	// zero if everything polled has been committed.
+	polled time.Time
 }
 
 // EpochOffset combines a record offset with the leader epoch the broker
@@ -1423,12 +2814,14 @@ func (g *groupConsumer) updateUncommitted(fetches Fetches) {
 	// only use head / committed in that case), or if we are greedily
 	// autocommitting (so that the latest head is available to autocommit).
 	setHead := g.cfg.autocommitDisable || g.cfg.autocommitGreedy
+	now := g.cfg.clock.Now()
 
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
 	for _, fetch := range fetches {
 		for _, topic := range fetch.Topics {
+			_, paused := g.pausedTopics[topic.Topic]
 
 			if debug {
 				fmt.Fprintf(&b, "%s[", topic.Topic)
@@ -1469,8 +2862,12 @@ func (g *groupConsumer) updateUncommitted(fetches Fetches) {
 				}
 
 				prior.dirty = set
-				if setHead {
+				if prior.polled.IsZero() {
+					prior.polled = now
+				}
+				if setHead && !paused {
 					prior.head = set
+					g.notifyCommittable()
 				}
 				topicOffsets[partition.Partition] = prior
 			}
@@ -1517,16 +2914,29 @@ func (g *groupConsumer) undirtyUncommitted() {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	for _, partitions := range g.uncommitted {
+	for topic, partitions := range g.uncommitted {
+		if _, paused := g.pausedTopics[topic]; paused {
+			continue
+		}
 		for partition, uncommit := range partitions {
 			if uncommit.dirty != uncommit.head {
 				uncommit.head = uncommit.dirty
 				partitions[partition] = uncommit
+				g.notifyCommittable()
 			}
 		}
 	}
 }
 
+// notifyCommittable, called under the group mu whenever head offsets move,
+// wakes the autocommit loop the first time there is something to commit if we
+// are autocommitting on the first poll.
+func (g *groupConsumer) notifyCommittable() {
+	if g.firstCommittable != nil {
+		g.firstCommittableOnce.Do(func() { close(g.firstCommittable) })
+	}
+}
+
 // updateCommitted updates the group's uncommitted map. This function triply
 // verifies that the resp matches the req as it should and that the req does
 // not somehow contain more than what is in our uncommitted map.
@@ -1625,6 +3035,13 @@ func (g *groupConsumer) updateCommitted(
 					*next = set
 				}
 			}
+			// If everything polled is now committed, nothing is
+			// outstanding. On a partial commit, we do not know when
+			// the remaining records were polled, so we keep the
+			// older stamp.
+			if !uncommit.committed.less(uncommit.dirty) {
+				uncommit.polled = time.Time{}
+			}
 			topic[respPart.Partition] = uncommit
 		}
 
@@ -1667,12 +3084,39 @@ func (g *groupConsumer) defaultCommitCallback(_ *Client, _ *kmsg.OffsetCommitReq
 }
 
 func (g *groupConsumer) loopCommit() {
-	ticker := time.NewTicker(g.cfg.autocommitInterval)
+	// If we have per-topic intervals, we tick at the smallest interval
+	// and only commit what is due each tick.
+	tick := g.cfg.autocommitInterval
+	for _, interval := range g.cfg.autocommitTopics {
+		if interval < tick {
+			tick = interval
+		}
+	}
+	ticker := g.cfg.clock.NewTicker(tick)
 	defer ticker.Stop()
 
+	// lastCommitted tracks when each topic was last included in a
+	// successful autocommit. This is only used with per-topic intervals,
+	// and is updated in the commit callback, hence the lock.
+	var (
+		lastCommittedMu sync.Mutex
+		lastCommitted   = make(map[string]time.Time)
+	)
+
+	// firstCommittable is nil unless autocommitting on the first poll.
+	// Once we commit early, we settle into the interval cadence.
+	first := g.firstCommittable
+
 	for {
+		var early bool
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
+			first = nil
+		case <-first:
+			first = nil
+			early = true
+			ticker.Reset(tick)
+			g.cfg.logger.Log(LogLevelDebug, "offsets are committable after the first poll, autocommitting early", "group", g.cfg.group)
 		case <-g.ctx.Done():
 			return
 		}
@@ -1688,13 +3132,99 @@ func (g *groupConsumer) loopCommit() {
 		// offsets.
 		g.mu.Lock()
 		if !g.blockAuto {
+			uncommitted := g.getUncommittedLocked(true, false)
+			if g.cfg.autocommitSkipSame {
+				g.dropUnchangedLocked(uncommitted)
+			}
+			onDone := g.cfg.commitCallback
+			if g.cfg.autocommitTopics != nil && !early {
+				now := g.cfg.clock.Now()
+				lastCommittedMu.Lock()
+				for topic := range uncommitted {
+					interval, exists := g.cfg.autocommitTopics[topic]
+					if !exists {
+						interval = g.cfg.autocommitInterval
+					}
+					if now.Sub(lastCommitted[topic]) < interval {
+						delete(uncommitted, topic)
+					}
+				}
+				lastCommittedMu.Unlock()
+
+				onDone = func(cl *Client, req *kmsg.OffsetCommitRequest, resp *kmsg.OffsetCommitResponse, err error) {
+					if err == nil {
+						lastCommittedMu.Lock()
+					topics:
+						for _, topic := range resp.Topics {
+							for _, partition := range topic.Partitions {
+								if partition.ErrorCode != 0 {
+									continue topics
+								}
+							}
+							lastCommitted[topic.Topic] = now
+						}
+						lastCommittedMu.Unlock()
+					}
+					g.cfg.commitCallback(cl, req, resp, err)
+				}
+			}
+			if fn := g.cfg.autocommitPartitionErrs; fn != nil {
+				inner := onDone
+				onDone = func(cl *Client, req *kmsg.OffsetCommitRequest, resp *kmsg.OffsetCommitResponse, err error) {
+					inner(cl, req, resp, err)
+					if err != nil {
+						return
+					}
+					if errs := commitPartitionErrs(resp); len(errs) > 0 && fn(cl, errs) {
+						g.rejoin(RejoinReasonCommitPartitionErrors)
+					}
+				}
+			}
 			g.cfg.logger.Log(LogLevelDebug, "autocommitting", "group", g.cfg.group)
-			g.commit(g.ctx, g.getUncommittedLocked(true, false), g.cfg.commitCallback)
+			g.commit(g.ctx, CommitOriginAuto, uncommitted, onDone)
 		}
 		g.mu.Unlock()
 	}
 }
 
+// dropUnchangedLocked removes every partition from uncommitted whose offset
+// is what is already committed, as well as any topic that becomes empty.
+func (g *groupConsumer) dropUnchangedLocked(uncommitted map[string]map[int32]EpochOffset) {
+	for topic, partitions := range uncommitted {
+		for partition, offset := range partitions {
+			if g.uncommitted[topic][partition].committed == offset {
+				delete(partitions, partition)
+			}
+		}
+		if len(partitions) == 0 {
+			delete(uncommitted, topic)
+		}
+	}
+}
+
+// commitPartitionErrs returns all partition errors in a commit response.
+func commitPartitionErrs(resp *kmsg.OffsetCommitResponse) map[string]map[int32]error {
+	var errs map[string]map[int32]error
+	for _, topic := range resp.Topics {
+		for _, partition := range topic.Partitions {
+			err := kerr.ErrorForCode(partition.ErrorCode)
+			if err == nil {
+				continue
+			}
+			if errs == nil {
+				errs = make(map[string]map[int32]error)
+			}
+			topicErrs := errs[topic.Topic]
+			if topicErrs == nil {
+				topicErrs = make(map[int32]error)
+				errs[topic.Topic] = topicErrs
+			}
+			topicErrs[partition.Partition] = err
+		}
+	}
+	return errs
+}
+
 // For SetOffsets, the gist of what follows:
 //
 // We need to set uncommitted.committed; that is the guarantee of this
@@ -1768,6 +3298,40 @@ func (cl *Client) UncommittedOffsets() map[string]map[int32]EpochOffset {
 	return nil
 }
 
+// OldestUncommittedAge returns how long ago the oldest polled but not yet
+// committed record was polled, or 0 if everything polled has been committed
+// or this client is not consuming as a group.
+//
+// This is a staleness signal: it shows whether autocommitting or manual
+// commits are falling behind, and roughly how much would be reprocessed if
+// this client crashed now. If a commit only partially covers what was polled
+// for a partition, the partition's age is kept from the earlier poll, meaning
+// this age errs on the side of being too old.
+func (cl *Client) OldestUncommittedAge() time.Duration {
+	g := cl.consumer.g
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var oldest time.Time
+	for _, partitions := range g.uncommitted {
+		for _, uncommit := range partitions {
+			if uncommit.polled.IsZero() {
+				continue
+			}
+			if oldest.IsZero() || uncommit.polled.Before(oldest) {
+				oldest = uncommit.polled
+			}
+		}
+	}
+	if oldest.IsZero() {
+		return 0
+	}
+	return g.cfg.clock.Now().Sub(oldest)
+}
+
 // CommittedOffsets returns the latest committed offsets. Committed offsets are
 // updated from commits or from joining a group and fetching offsets.
 //
@@ -1783,6 +3347,584 @@ func (cl *Client) CommittedOffsets() map[string]map[int32]EpochOffset {
 	return g.getUncommittedLocked(false, false)
 }
 
+// CommittedOffsetsCtx is like CommittedOffsets, but waits for the group to be
+// stable before returning. The group is stable once a group session has begun
+// heartbeating and offsets have been fetched for all assigned partitions.
+//
+// This returns early with the context's error if the context is canceled
+// before the group is stable, or with an error if the group is left or the
+// client is closed while waiting.
+func (cl *Client) CommittedOffsetsCtx(ctx context.Context) (map[string]map[int32]EpochOffset, error) {
+	g := cl.consumer.g
+	if g == nil {
+		return nil, errNotGroup
+	}
+	for {
+		g.mu.Lock()
+		if g.stableClosed {
+			defer g.mu.Unlock()
+			return g.getUncommittedLocked(false, false), nil
+		}
+		stable := g.stable
+		g.mu.Unlock()
+
+		select {
+		case <-stable:
+			// The session may have ended between stabilizing and
+			// us grabbing the lock; we loop to check again.
+		case <-g.ctx.Done():
+			return nil, errGroupLeft
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// waitAssigned waits until the group is stable and OnPartitionsAssigned has
+// returned for the stable session.
+func (g *groupConsumer) waitAssigned(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		stable, assigned := g.stable, g.sessionAssigned
+		stableClosed := g.stableClosed
+		g.mu.Unlock()
+
+		if !stableClosed {
+			select {
+			case <-stable:
+				continue // recheck that we are still stable
+			case <-g.ctx.Done():
+				return errGroupLeft
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		select {
+		case <-assigned:
+		case <-g.ctx.Done():
+			return errGroupLeft
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		// If the session ended while we waited for onAssigned, we
+		// wait for the next session.
+		g.mu.Lock()
+		same := g.stableClosed && g.stable == stable
+		g.mu.Unlock()
+		if same {
+			return nil
+		}
+	}
+}
+
+// FetchOffsetsForGroups fetches the committed offsets for all topics and
+// partitions in each of the input groups. This does not require the client to
+// be consuming as a group.
+//
+// This uses batched OffsetFetch requests (Kafka 3.0+), issuing one request per
+// group coordinator rather than one request per group. If a coordinator does
+// not support batched requests, offsets are fetched for that coordinator's
+// groups one group at a time.
+//
+// Partitions without a committed offset are not returned. If any group or
+// partition fails, this returns the error.
+func (cl *Client) FetchOffsetsForGroups(ctx context.Context, groups []string) (map[string]map[string]map[int32]EpochOffset, error) {
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	req := kmsg.NewPtrOffsetFetchRequest()
+	req.RequireStable = cl.requireStable()
+	for _, group := range groups {
+		reqGroup := kmsg.NewOffsetFetchRequestGroup()
+		reqGroup.Group = group
+		req.Groups = append(req.Groups, reqGroup)
+	}
+
+	fetched := make(map[string]map[string]map[int32]EpochOffset, len(groups))
+	var unbatched []string
+	for _, shard := range cl.RequestSharded(ctx, req) {
+		if shard.Err != nil {
+			return nil, shard.Err
+		}
+		resp := shard.Resp.(*kmsg.OffsetFetchResponse)
+		if resp.Version < 8 {
+			for _, group := range shard.Req.(*kmsg.OffsetFetchRequest).Groups {
+				unbatched = append(unbatched, group.Group)
+			}
+			continue
+		}
+		for i := range resp.Groups {
+			if err := addFetchedGroupOffsets(fetched, &resp.Groups[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, group := range unbatched {
+		req := kmsg.NewPtrOffsetFetchRequest()
+		req.Group = group
+		req.RequireStable = cl.requireStable()
+		resp, err := req.RequestWith(ctx, cl)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Groups) != 1 {
+			return nil, fmt.Errorf("offset fetch response for group %s unexpectedly has %d groups", group, len(resp.Groups))
+		}
+		if err := addFetchedGroupOffsets(fetched, &resp.Groups[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return fetched, nil
+}
+
+// SeekToCommitted fetches the group's committed offsets for all partitions
+// this member is currently consuming and resets consumption to those offsets.
+// This can be used to pick up offsets that were committed out of band (e.g., a
+// tool rewound the group) without restarting the client.
+//
+// Any buffered or in flight fetches for partitions that are reset are dropped,
+// meaning the next poll does not return records from before the new
+// position. Partitions that have no committed offset are left alone. If the
+// group rebalances while offsets are being fetched, nothing is reset and this
+// returns nil: the new group session fetches committed offsets itself.
+//
+// All of the caveats of SetOffsets apply.
+func (cl *Client) SeekToCommitted(ctx context.Context) error {
+	c := &cl.consumer
+	g := c.g
+	if g == nil {
+		return errNotGroup
+	}
+
+	g.mu.Lock()
+	generation := g.generation
+	consuming := g.consumingAssignedLocked()
+	g.mu.Unlock()
+	if len(consuming) == 0 {
+		return nil
+	}
+
+	fetched, err := g.fetchCommittedOffsets(ctx, consuming)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g.mu.Lock()
+	if g.generation != generation {
+		g.mu.Unlock()
+		g.cfg.logger.Log(LogLevelInfo, "group rebalanced while seeking to committed offsets, skipping seek", "group", g.cfg.group)
+		return nil
+	}
+	stillConsuming := make(map[string]map[int32]bool)
+	for topic, partitions := range g.consumingAssignedLocked() {
+		stillConsuming[topic] = make(map[int32]bool, len(partitions))
+		for _, partition := range partitions {
+			stillConsuming[topic][partition] = true
+		}
+	}
+	setOffsets := make(map[string]map[int32]EpochOffset, len(fetched))
+	for topic, partitions := range fetched {
+		for partition, offset := range partitions {
+			if offset.at < 0 {
+				continue // not committed
+			}
+			if !stillConsuming[topic][partition] {
+				continue
+			}
+			topicSet := setOffsets[topic]
+			if topicSet == nil {
+				topicSet = make(map[int32]EpochOffset, len(partitions))
+				setOffsets[topic] = topicSet
+			}
+			topicSet[partition] = EpochOffset{
+				Epoch:  offset.epoch,
+				Offset: offset.at,
+			}
+		}
+	}
+	g.mu.Unlock()
+
+	if assigns := g.getSetAssigns(setOffsets); len(assigns) > 0 {
+		c.assignPartitions(assigns, assignSetMatching, g.tps, "from SeekToCommitted")
+	}
+	return nil
+}
+
+// consumingAssignedLocked returns a copy of the partitions this member is
+// assigned, skipping topics that are no longer consumed (i.e., excluded).
+// This includes partitions that have not been polled or committed yet.
+func (g *groupConsumer) consumingAssignedLocked() map[string][]int32 {
+	consuming := make(map[string][]int32, len(g.nowAssigned))
+	for topic, partitions := range g.nowAssigned {
+		if _, excluded := g.excluded[topic]; excluded {
+			continue
+		}
+		if _, using := g.using[topic]; !using {
+			continue
+		}
+		consuming[topic] = append([]int32(nil), partitions...)
+	}
+	return consuming
+}
+
+// RewindBy resets consumption for every partition this member is currently
+// consuming to n offsets before the partition's committed offset. Offsets
+// are clamped to the partition's log start offset, such that rewinding by more
+// than a partition contains resets it to the start of the log. This is
+// meant for reprocessing: rather than reading CommittedOffsets and computing
+// absolute offsets, operators can rewind the whole assignment at once.
+//
+// Rewinding uses the committed offsets known to the client (see
+// CommittedOffsets); partitions without a committed offset are left alone.
+// The new offsets are set with an epoch of -1 because the epoch of the new
+// offset is unknown. If listing the log start or end offsets fails, nothing
+// is reset and this returns the error. If the group rebalances while offsets
+// are being listed, nothing is reset and this returns nil.
+//
+// All of the caveats of SetOffsets apply.
+func (cl *Client) RewindBy(ctx context.Context, n int64) error {
+	return cl.seekCommittedBy(ctx, -n, "from RewindBy")
+}
+
+// AdvanceBy is the inverse of RewindBy, resetting consumption for every
+// partition this member is currently consuming to n offsets after the
+// partition's committed offset, clamped to the partition's log end offset.
+//
+// All of the documentation on RewindBy applies to this function.
+func (cl *Client) AdvanceBy(ctx context.Context, n int64) error {
+	return cl.seekCommittedBy(ctx, n, "from AdvanceBy")
+}
+
+func (cl *Client) seekCommittedBy(ctx context.Context, delta int64, why string) error {
+	c := &cl.consumer
+	g := c.g
+	if g == nil {
+		return errNotGroup
+	}
+
+	g.mu.Lock()
+	generation := g.generation
+	committed := g.getUncommittedLocked(false, false)
+	g.mu.Unlock()
+
+	consuming := make(map[string][]int32, len(committed))
+	for topic, partitions := range committed {
+		for partition, offset := range partitions {
+			if offset.Offset >= 0 {
+				consuming[topic] = append(consuming[topic], partition)
+			}
+		}
+	}
+	if len(consuming) == 0 {
+		return nil
+	}
+
+	earliest, err := cl.listOffsetsAt(ctx, consuming, -2)
+	if err != nil {
+		return err
+	}
+	latest, err := cl.listOffsetsAt(ctx, consuming, -1)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g.mu.Lock()
+	if g.generation != generation {
+		g.mu.Unlock()
+		g.cfg.logger.Log(LogLevelInfo, "group rebalanced while seeking relative to committed offsets, skipping seek", "group", g.cfg.group, "why", why)
+		return nil
+	}
+	setOffsets := make(map[string]map[int32]EpochOffset, len(consuming))
+	for topic, partitions := range consuming {
+		for _, partition := range partitions {
+			if _, stillConsuming := g.uncommitted[topic][partition]; !stillConsuming {
+				continue
+			}
+			start, end := earliest[topic][partition].Offset, latest[topic][partition].Offset
+			at := committed[topic][partition].Offset + delta
+			if at < start {
+				at = start
+			}
+			if at > end {
+				at = end
+			}
+			topicSet := setOffsets[topic]
+			if topicSet == nil {
+				topicSet = make(map[int32]EpochOffset, len(partitions))
+				setOffsets[topic] = topicSet
+			}
+			topicSet[partition] = EpochOffset{Epoch: -1, Offset: at}
+		}
+	}
+	g.mu.Unlock()
+
+	if assigns := g.getSetAssigns(setOffsets); len(assigns) > 0 {
+		c.assignPartitions(assigns, assignSetMatching, g.tps, why)
+	}
+	return nil
+}
+
+// listOffsetsAt issues a ListOffsets request for all input partitions at the
+// given timestamp (-2 for the log start, -1 for the log end), returning each
+// offset with its leader epoch (-1 before ListOffsets v4). This returns the
+// first partition error encountered, or UnknownTopicOrPartition for any input
+// partition missing from the response.
+func (cl *Client) listOffsetsAt(ctx context.Context, partitions map[string][]int32, timestamp int64) (map[string]map[int32]EpochOffset, error) {
+	req := kmsg.NewPtrListOffsetsRequest()
+	for topic, ps := range partitions {
+		if len(ps) == 0 {
+			continue
+		}
+		reqTopic := kmsg.NewListOffsetsRequestTopic()
+		reqTopic.Topic = topic
+		for _, partition := range ps {
+			reqPartition := kmsg.NewListOffsetsRequestTopicPartition()
+			reqPartition.Partition = partition
+			reqPartition.Timestamp = timestamp
+			reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
+		}
+		req.Topics = append(req.Topics, reqTopic)
+	}
+	if len(req.Topics) == 0 {
+		return nil, nil
+	}
+
+	resp, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list offsets: %w", err)
+	}
+
+	offsets := make(map[string]map[int32]EpochOffset, len(resp.Topics))
+	for i := range resp.Topics {
+		t := &resp.Topics[i]
+		for j := range t.Partitions {
+			p := &t.Partitions[j]
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("unable to list offset for %s[%d]: %w", t.Topic, p.Partition, err)
+			}
+			to := offsets[t.Topic]
+			if to == nil {
+				to = make(map[int32]EpochOffset)
+				offsets[t.Topic] = to
+			}
+			to[p.Partition] = EpochOffset{
+				Epoch:  p.LeaderEpoch,
+				Offset: p.Offset,
+			}
+		}
+	}
+	for topic, ps := range partitions {
+		for _, partition := range ps {
+			if _, exists := offsets[topic][partition]; !exists {
+				return nil, fmt.Errorf("unable to list offset for %s[%d]: %w", topic, partition, kerr.UnknownTopicOrPartition)
+			}
+		}
+	}
+	return offsets, nil
+}
+
+// DetectTruncation compares the group's committed offsets (as returned from
+// CommittedOffsets) against the brokers' leader epochs, returning whether
+// each committed partition was truncated: that is, whether the log no longer
+// contains the committed offset in the committed epoch (KIP-320). A truncated
+// partition likely needs to be rewound, for example with SetOffsets.
+//
+// Partitions committed without an epoch are skipped and not returned. If the
+// brokers are too old to support leader epochs, this returns nothing.
+func (cl *Client) DetectTruncation(ctx context.Context) (map[string]map[int32]bool, error) {
+	if cl.consumer.g == nil {
+		return nil, errNotGroup
+	}
+	if !cl.supportsOffsetForLeaderEpoch() {
+		return nil, nil
+	}
+
+	committed := cl.CommittedOffsets()
+	req := kmsg.NewPtrOffsetForLeaderEpochRequest()
+	for topic, partitions := range committed {
+		reqTopic := kmsg.NewOffsetForLeaderEpochRequestTopic()
+		reqTopic.Topic = topic
+		for partition, eo := range partitions {
+			if eo.Epoch < 0 {
+				continue
+			}
+			reqPartition := kmsg.NewOffsetForLeaderEpochRequestTopicPartition()
+			reqPartition.Partition = partition
+			reqPartition.LeaderEpoch = eo.Epoch
+			reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
+		}
+		if len(reqTopic.Partitions) > 0 {
+			req.Topics = append(req.Topics, reqTopic)
+		}
+	}
+	if len(req.Topics) == 0 {
+		return nil, nil
+	}
+
+	resp, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return nil, fmt.Errorf("unable to detect truncation: %w", err)
+	}
+
+	truncated := make(map[string]map[int32]bool, len(req.Topics))
+	for i := range resp.Topics {
+		t := &resp.Topics[i]
+		for j := range t.Partitions {
+			p := &t.Partitions[j]
+			eo, exists := committed[t.Topic][p.Partition]
+			if !exists {
+				continue
+			}
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("unable to detect truncation for %s[%d]: %w", t.Topic, p.Partition, err)
+			}
+			// The end offset is the end of the committed epoch (or of
+			// the latest epoch before it). If our committed offset is
+			// past that end, the log was truncated.
+			tt := truncated[t.Topic]
+			if tt == nil {
+				tt = make(map[int32]bool)
+				truncated[t.Topic] = tt
+			}
+			tt[p.Partition] = p.EndOffset >= 0 && eo.Offset > p.EndOffset
+		}
+	}
+	return truncated, nil
+}
+
+// ListGroupOffsets fetches the committed offsets for all topics and partitions
+// in the given group without joining the group. This does not require the
+// client to be consuming as a group.
+//
+// Partitions without a committed offset are not returned. Leader epochs are
+// returned if the broker supports them (KIP-320), otherwise epochs are -1.
+func (cl *Client) ListGroupOffsets(ctx context.Context, group string) (map[string]map[int32]EpochOffset, error) {
+	req := kmsg.NewPtrOffsetFetchRequest()
+	req.Group = group
+	req.RequireStable = cl.requireStable()
+	req.Topics = nil // nil fetches all topics
+	resp, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Groups) != 1 {
+		return nil, fmt.Errorf("offset fetch response for group %s unexpectedly has %d groups", group, len(resp.Groups))
+	}
+	fetched := make(map[string]map[string]map[int32]EpochOffset, 1)
+	if err := addFetchedGroupOffsets(fetched, &resp.Groups[0]); err != nil {
+		return nil, err
+	}
+	return fetched[group], nil
+}
+
+// CommitGroupOffsets commits offsets for the given group using an explicit
+// generation and member ID, without joining the group. This does not require
+// the client to be consuming as a group, and is meant for tools that restore
+// previously backed up offsets.
+//
+// Kafka only accepts commits from outside the group if the group has no active
+// members: in that case, use a generation of -1 and an empty member ID. If the
+// group has members, the generation and member ID must match a live member,
+// otherwise Kafka replies with ILLEGAL_GENERATION or UNKNOWN_MEMBER_ID.
+//
+// This returns an error if the request fails, and otherwise returns the error
+// for every partition that failed to commit, or nil if all partitions were
+// committed.
+func (cl *Client) CommitGroupOffsets(
+	ctx context.Context,
+	group string,
+	generation int32,
+	memberID string,
+	offsets map[string]map[int32]EpochOffset,
+) (map[string]map[int32]error, error) {
+	req := kmsg.NewPtrOffsetCommitRequest()
+	req.Group = group
+	req.Generation = generation
+	req.MemberID = memberID
+	for topic, partitions := range offsets {
+		reqTopic := kmsg.NewOffsetCommitRequestTopic()
+		reqTopic.Topic = topic
+		for partition, eo := range partitions {
+			reqPartition := kmsg.NewOffsetCommitRequestTopicPartition()
+			reqPartition.Partition = partition
+			reqPartition.Offset = eo.Offset
+			reqPartition.LeaderEpoch = eo.Epoch
+			reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
+		}
+		req.Topics = append(req.Topics, reqTopic)
+	}
+	if len(req.Topics) == 0 {
+		return nil, nil
+	}
+
+	resp, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return nil, err
+	}
+	return commitPartitionErrs(resp), nil
+}
+
+func addFetchedGroupOffsets(fetched map[string]map[string]map[int32]EpochOffset, group *kmsg.OffsetFetchResponseGroup) error {
+	if err := kerr.ErrorForCode(group.ErrorCode); err != nil {
+		return fmt.Errorf("unable to fetch offsets for group %s: %w", group.Group, err)
+	}
+	groupOffsets := make(map[string]map[int32]EpochOffset, len(group.Topics))
+	for _, topic := range group.Topics {
+		for _, partition := range topic.Partitions {
+			if err := kerr.ErrorForCode(partition.ErrorCode); err != nil {
+				return fmt.Errorf("unable to fetch offsets for group %s topic %s partition %d: %w", group.Group, topic.Topic, partition.Partition, err)
+			}
+			if partition.Offset < 0 {
+				continue // not committed
+			}
+			topicOffsets := groupOffsets[topic.Topic]
+			if topicOffsets == nil {
+				topicOffsets = make(map[int32]EpochOffset, len(topic.Partitions))
+				groupOffsets[topic.Topic] = topicOffsets
+			}
+			topicOffsets[partition.Partition] = EpochOffset{
+				Epoch:  partition.LeaderEpoch,
+				Offset: partition.Offset,
+			}
+		}
+	}
+	fetched[group.Group] = groupOffsets
+	return nil
+}
+
+// setStable marks the current group session as stable, unblocking any
+// CommittedOffsetsCtx waiters.
+func (g *groupConsumer) setStable() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.stableClosed {
+		g.stableClosed = true
+		close(g.stable)
+	}
+}
+
+// setUnstable marks the current group session as no longer stable, causing
+// future CommittedOffsetsCtx calls to wait for the next stable session.
+func (g *groupConsumer) setUnstable() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stableClosed {
+		g.stableClosed = false
+		g.stable = make(chan struct{})
+	}
+}
+
 func (g *groupConsumer) getUncommitted(dirty bool) map[string]map[int32]EpochOffset {
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -1798,7 +3940,17 @@ func (g *groupConsumer) getUncommittedLocked(head, dirty bool) map[string]map[in
 	for topic, partitions := range g.uncommitted {
 		var topicUncommitted map[int32]EpochOffset
 		for partition, uncommit := range partitions {
-			if head && uncommit.dirty == uncommit.committed {
+			if head && g.cfg.commitTracker != nil {
+				offset, epoch, ok := g.cfg.commitTracker(topic, partition)
+				if !ok {
+					continue
+				}
+				tracked := EpochOffset{Epoch: epoch, Offset: offset}
+				if tracked == uncommit.committed {
+					continue
+				}
+				uncommit.head, uncommit.dirty = tracked, tracked
+			} else if head && uncommit.dirty == uncommit.committed {
 				continue
 			}
 			if topicUncommitted == nil {
@@ -1852,8 +4004,159 @@ func (g *groupConsumer) getUncommittedLocked(head, dirty bool) map[string]map[in
 // If you do not want to wait for this function to complete before continuing
 // processing records, you can call this function in a goroutine.
 func (cl *Client) CommitRecords(ctx context.Context, rs ...*Record) error {
-	// First build the offset commit map. We favor the latest epoch, then
-	// offset, if any records map to the same topic / partition.
+	return cl.commitOffsetsSyncErr(ctx, recordsCommitOffsets(rs))
+}
+
+// CommitRecordsOffsets is exactly CommitRecords, but this additionally returns
+// the offsets that were committed: the latest epoch and offset per partition
+// across all input records, limited to those partitions that Kafka confirmed
+// committing.
+//
+// If the returned error is non-nil, the returned offsets still contain any
+// partitions that were successfully committed before the error was found.
+//
+// All of the documentation on CommitRecords applies to this function.
+func (cl *Client) CommitRecordsOffsets(ctx context.Context, rs ...*Record) (map[string]map[int32]EpochOffset, error) {
+	return cl.commitOffsetsSyncConfirmed(ctx, recordsCommitOffsets(rs))
+}
+
+// CommitRecordsPerPartition commits the offsets contained within rs with one
+// synchronous commit request per partition, issuing the requests for every
+// partition concurrently. This returns the commit error for every partition
+// in rs, with a nil error for partitions that were committed successfully.
+//
+// This is useful if independent workers each process their own partitions
+// and want to commit their progress without contending with each other: the
+// other commit functions are serialized, with each commit canceling any prior
+// in flight commit. Commits through this function are only ordered per
+// partition: a new commit for a partition cancels (in which case the prior
+// commit's error is context.Canceled) and waits for any prior commit for the
+// same partition issued through this function, and commits for distinct
+// partitions do not wait on each other.
+//
+// Commits through this function are not ordered with respect to the other
+// commit functions, meaning this should be used with autocommitting
+// disabled. If RejectCommitRewinds is enabled, a rewinding partition returns
+// an *ErrCommitRewind and is not committed. All of the documentation on
+// CommitRecords regarding rebalances applies to this function.
+func (cl *Client) CommitRecordsPerPartition(ctx context.Context, rs ...*Record) map[string]map[int32]error {
+	offsets := recordsCommitOffsets(rs)
+	errs := make(map[string]map[int32]error, len(offsets))
+	for topic, partitions := range offsets {
+		errs[topic] = make(map[int32]error, len(partitions))
+	}
+
+	g := cl.consumer.g
+	if g == nil {
+		for topic, partitions := range offsets {
+			for partition := range partitions {
+				errs[topic][partition] = errNotGroup
+			}
+		}
+		return errs
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			topic, partition, offset := topic, partition, offset
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := g.commitPartition(ctx, topic, partition, offset)
+				mu.Lock()
+				errs[topic][partition] = err
+				mu.Unlock()
+			}()
+		}
+	}
+	wg.Wait()
+	return errs
+}
+
+// partitionCommit tracks an in flight commit from CommitRecordsPerPartition.
+type partitionCommit struct {
+	cancel func()
+	done   chan struct{}
+}
+
+// commitPartition issues a commit for a single partition, first canceling and
+// waiting for any prior commitPartition for the same partition.
+func (g *groupConsumer) commitPartition(ctx context.Context, topic string, partition int32, offset EpochOffset) error {
+	if err := g.checkCommitRewinds(map[string]map[int32]EpochOffset{topic: {partition: offset}}); err != nil {
+		return err
+	}
+
+	commitCtx, commitCancel := context.WithCancel(ctx)
+	defer commitCancel()
+	state := &partitionCommit{commitCancel, make(chan struct{})}
+
+	g.mu.Lock()
+	if g.partitionCommits == nil {
+		g.partitionCommits = make(map[string]map[int32]*partitionCommit)
+	}
+	if g.partitionCommits[topic] == nil {
+		g.partitionCommits[topic] = make(map[int32]*partitionCommit)
+	}
+	prior := g.partitionCommits[topic][partition]
+	g.partitionCommits[topic][partition] = state
+
+	req := kmsg.NewPtrOffsetCommitRequest()
+	req.Group = g.cfg.group
+	req.Generation = g.generation
+	req.MemberID = g.memberID
+	req.InstanceID = g.cfg.instanceID
+	reqTopic := kmsg.NewOffsetCommitRequestTopic()
+	reqTopic.Topic = topic
+	reqPartition := kmsg.NewOffsetCommitRequestTopicPartition()
+	reqPartition.Partition = partition
+	reqPartition.Offset = offset.Offset
+	reqPartition.LeaderEpoch = offset.Epoch
+	reqPartition.Metadata = g.commitMetadata(&req.MemberID)
+	reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
+	req.Topics = append(req.Topics, reqTopic)
+	g.mu.Unlock()
+
+	defer func() {
+		close(state.done)
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.partitionCommits[topic][partition] == state {
+			delete(g.partitionCommits[topic], partition)
+			if len(g.partitionCommits[topic]) == 0 {
+				delete(g.partitionCommits, topic)
+			}
+		}
+	}()
+
+	if prior != nil {
+		prior.cancel()
+		<-prior.done
+	}
+
+	resp, err := g.issueCommit(commitCtx, req)
+	g.cfg.hooks.each(func(h Hook) {
+		if h, ok := h.(HookGroupOffsetCommit); ok {
+			h.OnGroupOffsetCommit(CommitOriginManual, req, resp, err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	g.updateCommitted(req, resp)
+	if len(resp.Topics) != 1 || len(resp.Topics[0].Partitions) != 1 {
+		return fmt.Errorf("invalid commit response for topic %s partition %d: expected one topic and partition", topic, partition)
+	}
+	return kerr.ErrorForCode(resp.Topics[0].Partitions[0].ErrorCode)
+}
+
+// recordsCommitOffsets builds the offset commit map for CommitRecords{,Offsets}.
+// We favor the latest epoch, then offset, if any records map to the same topic
+// / partition.
+func recordsCommitOffsets(rs []*Record) map[string]map[int32]EpochOffset {
 	offsets := make(map[string]map[int32]EpochOffset)
 	for _, r := range rs {
 		toffsets := offsets[r.Topic]
@@ -1872,8 +4175,86 @@ func (cl *Client) CommitRecords(ctx context.Context, rs ...*Record) error {
 			r.Offset + 1, // need to advice to next offset to move forward
 		}
 	}
+	return offsets
+}
+
+// CommitRecord issues a synchronous offset commit for the single input record.
+// This is a fast path for CommitRecords when there is only one record to
+// commit, avoiding building and scanning the map that CommitRecords uses to
+// favor the latest epoch and offset per partition.
+//
+// All of the documentation on CommitRecords applies to this function.
+func (cl *Client) CommitRecord(ctx context.Context, r *Record) error {
+	return cl.commitOffsetsSyncErr(ctx, map[string]map[int32]EpochOffset{
+		r.Topic: {
+			r.Partition: {
+				r.LeaderEpoch,
+				r.Offset + 1,
+			},
+		},
+	})
+}
+
+// CommitResetToEarliest looks up the earliest offset for each input partition
+// and synchronously commits it, such that the next member to fetch committed
+// offsets for these partitions begins consuming from the start of the log.
+// This requires the client to be consuming as a group.
+//
+// This commits through this client's group membership, meaning the member must
+// be in the group for the commit to succeed. If this member is consuming any
+// of the input partitions, its own later commits (including autocommits) will
+// overwrite the reset; this is best used for partitions this member is not
+// consuming, or with autocommitting disabled followed by a rejoin.
+//
+// If looking up any earliest offset fails, including if a partition is
+// missing from the broker's response, nothing is committed. A reset is
+// an intentional rewind, so this is not checked by RejectCommitRewinds.
+func (cl *Client) CommitResetToEarliest(ctx context.Context, partitions map[string][]int32) error {
+	g := cl.consumer.g
+	if g == nil {
+		return errNotGroup
+	}
+
+	offsets, err := cl.listOffsetsAt(ctx, partitions, -2)
+	if err != nil {
+		return err
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	// We commit through the group directly rather than CommitOffsetsSync
+	// to skip the rewind check.
+	var rerr error
+	g.commitOffsetsSync(ctx, CommitOriginManual, offsets, func(_ *Client, _ *kmsg.OffsetCommitRequest, resp *kmsg.OffsetCommitResponse, err error) {
+		if err != nil {
+			rerr = err
+			return
+		}
+		for _, topic := range resp.Topics {
+			for _, partition := range topic.Partitions {
+				if err := kerr.ErrorForCode(partition.ErrorCode); err != nil && rerr == nil {
+					rerr = err
+				}
+			}
+		}
+	})
+	return rerr
+}
+
+// commitOffsetsSyncErr is the shared tail of CommitRecord{,s} and
+// CommitUncommittedOffsets: this issues a sync commit and returns the first
+// error encountered.
+func (cl *Client) commitOffsetsSyncErr(ctx context.Context, offsets map[string]map[int32]EpochOffset) error {
+	_, err := cl.commitOffsetsSyncConfirmed(ctx, offsets)
+	return err
+}
 
+// commitOffsetsSyncConfirmed issues a sync commit and returns the offsets that
+// were committed without error, as well as the first error encountered.
+func (cl *Client) commitOffsetsSyncConfirmed(ctx context.Context, offsets map[string]map[int32]EpochOffset) (map[string]map[int32]EpochOffset, error) {
 	var rerr error // return error
+	committed := make(map[string]map[int32]EpochOffset)
 
 	// Our client retries an OffsetCommitRequest as necessary if the first
 	// response partition has a retriable group error (group coordinator
@@ -1887,14 +4268,26 @@ func (cl *Client) CommitRecords(ctx context.Context, rs ...*Record) error {
 		for _, topic := range resp.Topics {
 			for _, partition := range topic.Partitions {
 				if err := kerr.ErrorForCode(partition.ErrorCode); err != nil {
-					rerr = err
-					return
+					if rerr == nil {
+						rerr = err
+					}
+					continue
+				}
+				sent, exists := offsets[topic.Topic][partition.Partition]
+				if !exists {
+					continue // Kafka replied with something we did not ask for, odd
+				}
+				tcommitted := committed[topic.Topic]
+				if tcommitted == nil {
+					tcommitted = make(map[int32]EpochOffset)
+					committed[topic.Topic] = tcommitted
 				}
+				tcommitted[partition.Partition] = sent
 			}
 		}
 	})
 
-	return rerr
+	return committed, rerr
 }
 
 // MarkCommitRecords marks records to be available for autocommitting. This
@@ -1938,6 +4331,7 @@ func (cl *Client) MarkCommitRecords(rs ...*Record) {
 		next := curPartitions[r.Partition]
 		if next.head.less(set) {
 			next.head = set
+			g.notifyCommittable()
 		}
 		if next.dirty.less(set) { // for sanity, but this should not happen
 			next.dirty = set
@@ -1968,24 +4362,71 @@ func (cl *Client) MarkCommitRecords(rs ...*Record) {
 // If you do not want to wait for this function to complete before continuing
 // processing records, you can call this function in a goroutine.
 func (cl *Client) CommitUncommittedOffsets(ctx context.Context) error {
-	// This function is just the tail end of CommitRecords just above.
-	var rerr error
-	cl.CommitOffsetsSync(ctx, cl.UncommittedOffsets(), func(_ *Client, _ *kmsg.OffsetCommitRequest, resp *kmsg.OffsetCommitResponse, err error) {
-		if err != nil {
-			rerr = err
-			return
+	return cl.commitOffsetsSyncErr(ctx, cl.UncommittedOffsets())
+}
+
+// CommitUntilDrained repeatedly commits all dirty uncommitted offsets until
+// there is nothing left to commit, returning nil once drained, the first
+// commit error, or the context error if the context is canceled first. If a
+// commit succeeds but exactly the same offsets remain to be committed (for
+// example, because the group rebalanced and the commit was for an old
+// generation), this returns an error rather than committing them again.
+//
+// Each commit is a CommitOffsetsSync, meaning autocommitting is blocked while
+// each commit is in flight, and any in flight autocommit or CommitOffsets is
+// canceled and waited on before committing. Offsets that are polled while
+// draining are committed in the next iteration; to truly drain, stop polling
+// before calling this.
+//
+// This is useful during a controlled shutdown, in place of looping over
+// CommitUncommittedOffsets and UncommittedOffsets yourself.
+func (cl *Client) CommitUntilDrained(ctx context.Context) error {
+	g := cl.consumer.g
+	if g == nil {
+		return errNotGroup
+	}
+	var prior map[string]map[int32]EpochOffset
+	for {
+		uncommitted := g.getUncommitted(true)
+		if len(uncommitted) == 0 {
+			return nil
+		}
+		// If our last commit succeeded but did not update what we
+		// consider committed (e.g., the group rebalanced and the
+		// commit was for an old generation), we would otherwise spin
+		// committing the same offsets until the context is canceled.
+		if sameCommitOffsets(prior, uncommitted) {
+			return errCommitNoProgress
+		}
+		if err := cl.commitOffsetsSyncErr(ctx, uncommitted); err != nil {
+			return err
 		}
+		prior = uncommitted
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+}
 
-		for _, topic := range resp.Topics {
-			for _, partition := range topic.Partitions {
-				if err := kerr.ErrorForCode(partition.ErrorCode); err != nil {
-					rerr = err
-					return
-				}
+// sameCommitOffsets returns whether l and r contain exactly the same offsets.
+func sameCommitOffsets(l, r map[string]map[int32]EpochOffset) bool {
+	if len(l) != len(r) {
+		return false
+	}
+	for topic, lps := range l {
+		rps, exists := r[topic]
+		if !exists || len(lps) != len(rps) {
+			return false
+		}
+		for partition, lo := range lps {
+			if ro, exists := rps[partition]; !exists || lo != ro {
+				return false
 			}
 		}
-	})
-	return rerr
+	}
+	return true
 }
 
 // CommitOffsetsSync cancels any active CommitOffsets, begins a commit that
@@ -2020,11 +4461,136 @@ func (cl *Client) CommitOffsetsSync(
 		onDone(cl, kmsg.NewPtrOffsetCommitRequest(), kmsg.NewPtrOffsetCommitResponse(), nil)
 		return
 	}
-	g.commitOffsetsSync(ctx, uncommitted, onDone)
+	if err := g.checkCommitRewinds(uncommitted); err != nil {
+		onDone(cl, kmsg.NewPtrOffsetCommitRequest(), kmsg.NewPtrOffsetCommitResponse(), err)
+		return
+	}
+	g.commitOffsetsSync(ctx, CommitOriginManual, uncommitted, onDone)
+}
+
+// CommitOffsetsSyncResult is like CommitOffsetsSync, but rather than calling
+// an onDone callback, this returns the commit's response and request error
+// directly. Per-partition errors are not returned as the error: they must be
+// checked in the response, which contains every partition that was
+// committed. If there is nothing to commit, this returns an empty response
+// and no error.
+//
+// All of the documentation on CommitOffsetsSync applies to this function.
+func (cl *Client) CommitOffsetsSyncResult(ctx context.Context, uncommitted map[string]map[int32]EpochOffset) (*kmsg.OffsetCommitResponse, error) {
+	var (
+		resp *kmsg.OffsetCommitResponse
+		err  error
+	)
+	cl.CommitOffsetsSync(ctx, uncommitted, func(_ *Client, _ *kmsg.OffsetCommitRequest, r *kmsg.OffsetCommitResponse, e error) {
+		resp, err = r, e
+	})
+	return resp, err
+}
+
+// CommitOffsetsSyncWithRetry is like CommitOffsetsSync, but if the commit
+// fails with a retriable error (either a request error or any partition
+// error), this re-issues the whole commit, up to maxAttempts total attempts
+// and for up to maxWait total. The onDone callback is called once with the
+// last attempt's request, response, and error.
+//
+// This is useful for a best effort commit within a bounded time, such as
+// when shutting down: the maxWait also bounds the client's internal retries
+// for each attempt. A non-positive maxAttempts means a single attempt, and a
+// non-positive maxWait means no bound beyond the input context. Attempts are
+// spaced out with the client's RetryBackoffFn.
+//
+// All of the documentation on CommitOffsetsSync applies to this function.
+func (cl *Client) CommitOffsetsSyncWithRetry(
+	ctx context.Context,
+	uncommitted map[string]map[int32]EpochOffset,
+	maxAttempts int,
+	maxWait time.Duration,
+	onDone func(*Client, *kmsg.OffsetCommitRequest, *kmsg.OffsetCommitResponse, error),
+) {
+	if onDone == nil {
+		onDone = func(*Client, *kmsg.OffsetCommitRequest, *kmsg.OffsetCommitResponse, error) {}
+	}
+	if maxWait > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, maxWait)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		var (
+			req   *kmsg.OffsetCommitRequest
+			resp  *kmsg.OffsetCommitResponse
+			err   error
+			retry bool
+		)
+		cl.CommitOffsetsSync(ctx, uncommitted, func(_ *Client, dreq *kmsg.OffsetCommitRequest, dresp *kmsg.OffsetCommitResponse, derr error) {
+			req, resp, err = dreq, dresp, derr
+			if err != nil {
+				retry = kerr.IsRetriable(err) || isRetriableBrokerErr(err)
+				return
+			}
+			for _, topic := range resp.Topics {
+				for _, partition := range topic.Partitions {
+					if perr := kerr.ErrorForCode(partition.ErrorCode); perr != nil {
+						if !kerr.IsRetriable(perr) {
+							retry = false
+							return
+						}
+						retry = true
+					}
+				}
+			}
+		})
+
+		if !retry || attempt >= maxAttempts {
+			onDone(cl, req, resp, err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			onDone(cl, req, resp, err)
+			return
+		case <-cl.cfg.clock.After(cl.cfg.retryBackoff(attempt)):
+		}
+	}
+}
+
+// checkCommitRewinds returns an *ErrCommitRewind if RejectCommitRewinds is
+// enabled and any offset in uncommitted is before what we know is committed.
+func (g *groupConsumer) checkCommitRewinds(uncommitted map[string]map[int32]EpochOffset) error {
+	if !g.cfg.rejectCommitRewinds {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var rewinds map[string]map[int32]EpochOffset
+	for topic, partitions := range uncommitted {
+		for partition, offset := range partitions {
+			prior, exists := g.uncommitted[topic][partition]
+			if !exists || offset.Offset >= prior.committed.Offset {
+				continue
+			}
+			if rewinds == nil {
+				rewinds = make(map[string]map[int32]EpochOffset)
+			}
+			if rewinds[topic] == nil {
+				rewinds[topic] = make(map[int32]EpochOffset)
+			}
+			rewinds[topic][partition] = offset
+		}
+	}
+	if rewinds != nil {
+		g.cfg.logger.Log(LogLevelWarn, "rejecting commit that would rewind committed offsets", "group", g.cfg.group, "rewinds", rewinds)
+		return &ErrCommitRewind{rewinds}
+	}
+	return nil
 }
 
 func (g *groupConsumer) commitOffsetsSync(
 	ctx context.Context,
+	origin CommitOrigin,
 	uncommitted map[string]map[int32]EpochOffset,
 	onDone func(*Client, *kmsg.OffsetCommitRequest, *kmsg.OffsetCommitResponse, error),
 ) {
@@ -2057,7 +4623,7 @@ func (g *groupConsumer) commitOffsetsSync(
 		g.blockAuto = false
 	}
 
-	g.commit(ctx, uncommitted, unblockAuto)
+	g.commit(ctx, origin, uncommitted, unblockAuto)
 }
 
 // CommitOffsets commits the given offsets for a group, calling onDone with the
@@ -2077,6 +4643,12 @@ func (g *groupConsumer) commitOffsetsSync(
 //
 // It is invalid to use this function to commit offsets for a transaction.
 //
+// Offsets are committed (and fetched) by topic name, not topic ID: the
+// OffsetCommit and OffsetFetch versions this client supports (v8) do not
+// have topic IDs. If a topic is deleted and recreated with the same name,
+// offsets committed for the old topic apply to the new topic; delete the
+// group's offsets for the topic when recreating it.
+//
 // Note that this function ensures absolute ordering of commit requests by
 // canceling prior requests and ensuring they are done before executing a new
 // one. This means, for absolute control, you can use this function to
@@ -2114,6 +4686,10 @@ func (cl *Client) CommitOffsets(
 		onDone(cl, kmsg.NewPtrOffsetCommitRequest(), kmsg.NewPtrOffsetCommitResponse(), nil)
 		return
 	}
+	if err := g.checkCommitRewinds(uncommitted); err != nil {
+		onDone(cl, kmsg.NewPtrOffsetCommitRequest(), kmsg.NewPtrOffsetCommitResponse(), err)
+		return
+	}
 
 	g.syncCommitMu.RLock() // block sync commit, but allow other concurrent Commit to cancel us
 
@@ -2133,7 +4709,68 @@ func (cl *Client) CommitOffsets(
 		g.blockAuto = false
 	}
 
-	g.commit(ctx, uncommitted, unblockAuto)
+	g.commit(ctx, CommitOriginManual, uncommitted, unblockAuto)
+}
+
+// CommitOffsetsQueued is like CommitOffsets, but rather than canceling any
+// in flight commit, this queues the commit to be issued once all previously
+// queued commits are done. Every queued commit is attempted, in order.
+//
+// The queue is bounded, with a default depth of 16 (see CommitQueueDepth). If
+// the queue is full, this function blocks until a queued commit finishes or
+// the context is canceled, in which case onDone is called with the context's
+// error. Once queued, this function returns without waiting for the commit.
+//
+// Each queued commit is issued as if with CommitOffsetsSync, meaning a queued
+// commit blocks autocommitting and CommitOffsets while it is being issued, and
+// cancels any in flight async commit when it begins. The trade off versus
+// CommitOffsets is throughput: cancel-prior committing converges on the latest
+// offsets quickly, whereas queued committing issues every commit and can fall
+// behind if commits are requested faster than they complete.
+func (cl *Client) CommitOffsetsQueued(
+	ctx context.Context,
+	uncommitted map[string]map[int32]EpochOffset,
+	onDone func(*Client, *kmsg.OffsetCommitRequest, *kmsg.OffsetCommitResponse, error),
+) {
+	if onDone == nil {
+		onDone = func(*Client, *kmsg.OffsetCommitRequest, *kmsg.OffsetCommitResponse, error) {}
+	}
+
+	g := cl.consumer.g
+	if g == nil {
+		onDone(cl, kmsg.NewPtrOffsetCommitRequest(), kmsg.NewPtrOffsetCommitResponse(), errNotGroup)
+		return
+	}
+	if len(uncommitted) == 0 {
+		onDone(cl, kmsg.NewPtrOffsetCommitRequest(), kmsg.NewPtrOffsetCommitResponse(), nil)
+		return
+	}
+	if err := g.checkCommitRewinds(uncommitted); err != nil {
+		onDone(cl, kmsg.NewPtrOffsetCommitRequest(), kmsg.NewPtrOffsetCommitResponse(), err)
+		return
+	}
+
+	select {
+	case g.commitQueueSem <- struct{}{}:
+	case <-ctx.Done():
+		onDone(cl, kmsg.NewPtrOffsetCommitRequest(), kmsg.NewPtrOffsetCommitResponse(), ctx.Err())
+		return
+	}
+
+	g.commitQueueMu.Lock()
+	prior := g.commitQueueTail
+	done := make(chan struct{})
+	g.commitQueueTail = done
+	g.commitQueueMu.Unlock()
+
+	go func() {
+		defer close(done)
+		defer func() { <-g.commitQueueSem }()
+		if prior != nil {
+			<-prior
+		}
+		g.commitOffsetsSync(ctx, CommitOriginManual, uncommitted, onDone)
+	}()
 }
 
 // defaultRevoke commits the last fetched offsets and waits for the commit to
@@ -2142,13 +4779,65 @@ func (cl *Client) CommitOffsets(
 //
 // Note that the heartbeat loop invalidates all buffered, unpolled fetches
 // before revoking, meaning this truly will commit all polled fetches.
-func (g *groupConsumer) defaultRevoke(context.Context, *Client, map[string][]int32) {
+func (g *groupConsumer) defaultRevoke(ctx context.Context, _ *Client, _ map[string][]int32) {
 	if !g.cfg.autocommitDisable {
-		// We use the client's context rather than the group context,
-		// because this could come from the group being left. The group
-		// context will already be canceled.
-		g.commitOffsetsSync(g.cl.ctx, g.getUncommitted(false), g.cfg.commitCallback)
+		// The input context is from revokeCtx: the client's context
+		// rather than the group context, optionally with a timeout.
+		g.commitOffsetsSync(ctx, CommitOriginRevoke, g.getUncommitted(false), g.cfg.commitCallback)
+	}
+}
+
+// CommitOrigin is where an offset commit originated, passed to
+// HookGroupOffsetCommit.
+type CommitOrigin int8
+
+const (
+	// CommitOriginManual means the commit was issued through any of the
+	// CommitXyz functions, including commits issued within a custom
+	// OnPartitionsRevoked.
+	CommitOriginManual CommitOrigin = iota
+	// CommitOriginAuto means the commit was issued by the autocommit
+	// loop.
+	CommitOriginAuto
+	// CommitOriginRevoke means the commit was the default commit in
+	// OnPartitionsRevoked, or the commit issued on behalf of
+	// OnPartitionsRevokedCommit.
+	CommitOriginRevoke
+)
+
+func (o CommitOrigin) String() string {
+	switch o {
+	case CommitOriginManual:
+		return "manual"
+	case CommitOriginAuto:
+		return "autocommit"
+	case CommitOriginRevoke:
+		return "revoke"
+	default:
+		return "unknown"
+	}
+}
+
+// revokeCommit is the onRevoked function when using OnPartitionsRevokedCommit:
+// we call the user function and then synchronously commit what it returned.
+func (g *groupConsumer) revokeCommit(ctx context.Context, cl *Client, revoked map[string][]int32) {
+	offsets := g.cfg.onRevokedCommit(ctx, cl, revoked)
+	if len(offsets) == 0 {
+		return
+	}
+	g.commitOffsetsSync(ctx, CommitOriginRevoke, offsets, g.cfg.commitCallback)
+}
+
+// commitMetadata returns the metadata to use for every committed partition:
+// nil if omitting, the user's metadata if set, or the member ID.
+func (g *groupConsumer) commitMetadata(memberID *string) *string {
+	switch {
+	case g.cfg.omitCommitMetadata:
+		return nil
+	case g.cfg.commitMetadata != nil:
+		return g.cfg.commitMetadata
 	}
+	return memberID
 }
 
 // commit is the logic for Commit; see Commit's documentation
@@ -2156,6 +4845,7 @@ func (g *groupConsumer) defaultRevoke(context.Context, *Client, map[string][]int
 // This is called under the groupConsumer's lock.
 func (g *groupConsumer) commit(
 	ctx context.Context,
+	origin CommitOrigin,
 	uncommitted map[string]map[int32]EpochOffset,
 	onDone func(*Client, *kmsg.OffsetCommitRequest, *kmsg.OffsetCommitResponse, error),
 ) {
@@ -2216,22 +4906,213 @@ func (g *groupConsumer) commit(
 				reqPartition.Partition = partition
 				reqPartition.Offset = eo.Offset
 				reqPartition.LeaderEpoch = eo.Epoch // KIP-320
-				reqPartition.Metadata = &req.MemberID
+				reqPartition.Metadata = g.commitMetadata(&req.MemberID)
 				reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
 			}
 			req.Topics = append(req.Topics, reqTopic)
 		}
 
-		resp, err := req.RequestWith(commitCtx, g.cl)
-		if err != nil {
-			onDone(g.cl, req, nil, err)
-			return
+		// If the commit is split, we issue each request sequentially
+		// and stop at the first request failure; the response passed
+		// to onDone is the merge of every successful response, and is
+		// nil if the first request failed.
+		var (
+			resp *kmsg.OffsetCommitResponse
+			err  error
+		)
+		for _, chunk := range g.splitCommit(req) {
+			chunkResp, chunkErr := g.issueCommit(commitCtx, chunk)
+			g.cfg.hooks.each(func(h Hook) {
+				if h, ok := h.(HookGroupOffsetCommit); ok {
+					h.OnGroupOffsetCommit(origin, chunk, chunkResp, chunkErr)
+				}
+			})
+			if chunkErr != nil {
+				err = chunkErr
+				break
+			}
+			g.updateCommitted(chunk, chunkResp)
+			resp = mergeCommitResps(resp, chunkResp)
 		}
-		g.updateCommitted(req, resp)
-		onDone(g.cl, req, resp, nil)
+		g.setLastCommitErrs(req, resp, err)
+		onDone(g.cl, req, resp, err)
 	}()
 }
 
+// offsetsClient returns the client that committed offsets are fetched from
+// and committed to; see OffsetsClient.
+func (g *groupConsumer) offsetsClient() *Client {
+	if g.cfg.offsetsClient != nil {
+		return g.cfg.offsetsClient
+	}
+	return g.cl
+}
+
+// issueCommit issues req, either to our own cluster or to the OffsetsClient.
+// The other cluster does not know of our membership, so we send a copy of the
+// request with the generation and member stripped; req itself is left as is
+// so that updateCommitted still matches it against our generation.
+func (g *groupConsumer) issueCommit(ctx context.Context, req *kmsg.OffsetCommitRequest) (*kmsg.OffsetCommitResponse, error) {
+	if g.cfg.offsetsClient == nil {
+		return req.RequestWith(ctx, g.cl)
+	}
+	stripped := *req
+	stripped.Generation = -1
+	stripped.MemberID = ""
+	stripped.InstanceID = nil
+	return stripped.RequestWith(ctx, g.cfg.offsetsClient)
+}
+
+// splitCommit splits a commit request into requests of at most
+// CommitMaxPartitionsPerRequest partitions each, or returns the request as is
+// if it does not need to be split.
+func (g *groupConsumer) splitCommit(req *kmsg.OffsetCommitRequest) []*kmsg.OffsetCommitRequest {
+	max := g.cfg.commitMaxPartitions
+	if max <= 0 {
+		return []*kmsg.OffsetCommitRequest{req}
+	}
+	var total int
+	for _, topic := range req.Topics {
+		total += len(topic.Partitions)
+	}
+	if total <= max {
+		return []*kmsg.OffsetCommitRequest{req}
+	}
+
+	var (
+		chunks []*kmsg.OffsetCommitRequest
+		chunk  *kmsg.OffsetCommitRequest
+		n      int
+	)
+	for _, topic := range req.Topics {
+		partitions := topic.Partitions
+		for len(partitions) > 0 {
+			if chunk == nil || n == max {
+				dup := *req
+				dup.Topics = nil
+				chunk = &dup
+				chunks = append(chunks, chunk)
+				n = 0
+			}
+			take := max - n
+			if take > len(partitions) {
+				take = len(partitions)
+			}
+			reqTopic := topic
+			reqTopic.Partitions = partitions[:take:take]
+			chunk.Topics = append(chunk.Topics, reqTopic)
+			partitions = partitions[take:]
+			n += take
+		}
+	}
+	return chunks
+}
+
+// mergeCommitResps merges src into dst, returning src if dst is nil.
+func mergeCommitResps(dst, src *kmsg.OffsetCommitResponse) *kmsg.OffsetCommitResponse {
+	if dst == nil {
+		return src
+	}
+	if src.ThrottleMillis > dst.ThrottleMillis {
+		dst.ThrottleMillis = src.ThrottleMillis
+	}
+	for _, srcTopic := range src.Topics {
+		var merged bool
+		for i := range dst.Topics {
+			if dstTopic := &dst.Topics[i]; dstTopic.Topic == srcTopic.Topic {
+				dstTopic.Partitions = append(dstTopic.Partitions, srcTopic.Partitions...)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			dst.Topics = append(dst.Topics, srcTopic)
+		}
+	}
+	return dst
+}
+
+// setLastCommitErrs saves the errors from a finished commit. A request error
+// applies to every partition in the request. Canceled commits are skipped,
+// since they are superseded by the commit that canceled them.
+//
+// If a split commit failed partway, resp contains the responses for what was
+// committed, and err applies to every partition not in resp.
+func (g *groupConsumer) setLastCommitErrs(req *kmsg.OffsetCommitRequest, resp *kmsg.OffsetCommitResponse, err error) {
+	if err == context.Canceled && resp == nil {
+		return
+	}
+
+	var errs map[string]map[int32]error
+	add := func(topic string, partition int32, err error) {
+		if errs == nil {
+			errs = make(map[string]map[int32]error)
+		}
+		if errs[topic] == nil {
+			errs[topic] = make(map[int32]error)
+		}
+		errs[topic][partition] = err
+	}
+	responded := make(map[string]map[int32]bool)
+	if resp != nil {
+		for _, topic := range resp.Topics {
+			for _, partition := range topic.Partitions {
+				if responded[topic.Topic] == nil {
+					responded[topic.Topic] = make(map[int32]bool)
+				}
+				responded[topic.Topic][partition.Partition] = true
+				if err := kerr.ErrorForCode(partition.ErrorCode); err != nil {
+					add(topic.Topic, partition.Partition, err)
+				}
+			}
+		}
+	}
+	if err != nil {
+		for _, topic := range req.Topics {
+			for _, partition := range topic.Partitions {
+				if !responded[topic.Topic][partition.Partition] {
+					add(topic.Topic, partition.Partition, err)
+				}
+			}
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lastCommitErrs = errs
+}
+
+// LastCommitErrors returns the per-partition errors from the most recent
+// commit, whether that commit was an autocommit, a revoke commit, or a manual
+// commit. If the commit request itself failed, every partition in the commit
+// maps to the request error. This returns nil if the most recent commit had no
+// errors, if nothing has been committed, or if the client is not consuming as a
+// group. Commits that were canceled by a later commit are ignored.
+//
+// This can be used for health checks without replacing the default commit
+// callback (see AutoCommitCallback).
+func (cl *Client) LastCommitErrors() map[string]map[int32]error {
+	g := cl.consumer.g
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.lastCommitErrs == nil {
+		return nil
+	}
+	dup := make(map[string]map[int32]error, len(g.lastCommitErrs))
+	for topic, partitions := range g.lastCommitErrs {
+		dupPartitions := make(map[int32]error, len(partitions))
+		for partition, err := range partitions {
+			dupPartitions[partition] = err
+		}
+		dup[topic] = dupPartitions
+	}
+	return dup
+}
+
 type reNews struct {
 	added   map[string][]string
 	skipped []string
@@ -0,0 +1,1076 @@
+package kgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// commitSync issues a commit through g.commit and waits for it to finish.
+func (g *groupConsumer) commitSync(offsets map[string]map[int32]EpochOffset) (*kmsg.OffsetCommitResponse, error) {
+	var (
+		resp *kmsg.OffsetCommitResponse
+		err  error
+		done = make(chan struct{})
+	)
+	g.mu.Lock()
+	g.commit(context.Background(), CommitOriginManual, offsets, func(_ *Client, _ *kmsg.OffsetCommitRequest, r *kmsg.OffsetCommitResponse, e error) {
+		resp, err = r, e
+		close(done)
+	})
+	g.mu.Unlock()
+	<-done
+	return resp, err
+}
+
+// okCommitResponse returns a successful response for every partition in req.
+func okCommitResponse(req *kmsg.OffsetCommitRequest) *kmsg.OffsetCommitResponse {
+	resp := kmsg.NewPtrOffsetCommitResponse()
+	for _, reqTopic := range req.Topics {
+		respTopic := kmsg.NewOffsetCommitResponseTopic()
+		respTopic.Topic = reqTopic.Topic
+		for _, reqPartition := range reqTopic.Partitions {
+			respPartition := kmsg.NewOffsetCommitResponseTopicPartition()
+			respPartition.Partition = reqPartition.Partition
+			respTopic.Partitions = append(respTopic.Partitions, respPartition)
+		}
+		resp.Topics = append(resp.Topics, respTopic)
+	}
+	return resp
+}
+
+func TestOffsetsClientCommit(t *testing.T) {
+	var sent []*kmsg.OffsetCommitRequest
+	offsetsCl, err := NewClient(
+		SeedBrokers("127.0.0.1:1"),
+		InterceptRequests(func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+			commit, ok := req.(*kmsg.OffsetCommitRequest)
+			if !ok {
+				return nil, nil
+			}
+			sent = append(sent, commit)
+			return okCommitResponse(commit), nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offsetsCl.Close()
+
+	g := newStubGroup(t, func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+		if _, ok := req.(*kmsg.OffsetCommitRequest); ok {
+			t.Error("commit unexpectedly issued to the primary client")
+		}
+		return nil, nil
+	}, OffsetsClient(offsetsCl))
+	g.generation = 3
+	g.memberID = "member"
+	g.uncommitted = uncommitted{"t": {0: {
+		head:      EpochOffset{Epoch: 1, Offset: 10},
+		committed: EpochOffset{Epoch: -1, Offset: -1},
+	}}}
+
+	if _, err := g.commitSync(map[string]map[int32]EpochOffset{"t": {0: {Epoch: 1, Offset: 10}}}); err != nil {
+		t.Fatalf("unexpected commit err: %v", err)
+	}
+
+	if len(sent) != 1 {
+		t.Fatalf("got %d commits to the offsets client, expected 1", len(sent))
+	}
+	req := sent[0]
+	if req.Generation != -1 || req.MemberID != "" || req.InstanceID != nil {
+		t.Errorf("commit sent with membership: generation %d, member %q, instance %v", req.Generation, req.MemberID, req.InstanceID)
+	}
+	if meta := req.Topics[0].Partitions[0].Metadata; meta == nil || *meta != "member" {
+		t.Errorf("got commit metadata %v, expected the member ID", meta)
+	}
+	if got, exp := g.uncommitted["t"][0].committed, (EpochOffset{Epoch: 1, Offset: 10}); got != exp {
+		t.Errorf("got committed %v, expected %v", got, exp)
+	}
+}
+
+func TestCommitUntilDrainedNoProgress(t *testing.T) {
+	var commits int
+	g := newStubGroup(t, func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+		if _, ok := req.(*kmsg.OffsetCommitRequest); !ok {
+			return nil, nil
+		}
+		commits++
+		return kmsg.NewPtrOffsetCommitResponse(), nil // no topics: nothing is marked committed
+	})
+	g.uncommitted = uncommitted{"t": {0: {
+		dirty:     EpochOffset{Epoch: 1, Offset: 10},
+		head:      EpochOffset{Epoch: 1, Offset: 10},
+		committed: EpochOffset{Epoch: -1, Offset: -1},
+	}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := g.cl.CommitUntilDrained(ctx); err != errCommitNoProgress {
+		t.Errorf("got err %v, expected %v", err, errCommitNoProgress)
+	}
+	if commits != 1 {
+		t.Errorf("got %d commits, expected 1", commits)
+	}
+}
+
+// stubGroupOpts returns options for a client consuming group "group" that
+// never reaches a broker: metadata never loads, so the group is never managed,
+// allowing the group logic to be tested directly. Leaving the group on Close
+// is stubbed, and every other request is passed to intercept, if non-nil (see
+// InterceptRequests).
+func stubGroupOpts(intercept func(context.Context, kmsg.Request) (kmsg.Response, error), opts ...Opt) []Opt {
+	return append([]Opt{
+		SeedBrokers("127.0.0.1:1"),
+		ConsumerGroup("group"),
+		ConsumeTopics("t"),
+		InterceptRequests(func(ctx context.Context, req kmsg.Request) (kmsg.Response, error) {
+			if _, ok := req.(*kmsg.LeaveGroupRequest); ok {
+				return kmsg.NewPtrLeaveGroupResponse(), nil
+			}
+			if intercept != nil {
+				return intercept(ctx, req)
+			}
+			return nil, nil
+		}),
+	}, opts...)
+}
+
+// newStubGroup returns the group of a client built with stubGroupOpts. The
+// client is closed when the test ends.
+func newStubGroup(t *testing.T, intercept func(context.Context, kmsg.Request) (kmsg.Response, error), opts ...Opt) *groupConsumer {
+	t.Helper()
+	cl, err := NewClient(stubGroupOpts(intercept, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cl.Close)
+	return cl.consumer.g
+}
+
+func TestEndManageSession(t *testing.T) {
+	type calls struct{ revoked, lost []map[string][]int32 }
+	record := func(c *calls) []Opt {
+		return []Opt{
+			OnPartitionsRevoked(func(_ context.Context, _ *Client, m map[string][]int32) { c.revoked = append(c.revoked, m) }),
+			OnPartitionsLost(func(_ context.Context, _ *Client, m map[string][]int32) { c.lost = append(c.lost, m) }),
+		}
+	}
+	owned := map[string][]int32{"t": {0, 1}}
+
+	for _, test := range []struct {
+		name     string
+		txn      bool
+		noLost   bool
+		err      error
+		assigned map[string][]int32
+		revoked  bool
+		lost     bool
+	}{
+		{name: "leave revokes", err: context.Canceled, assigned: owned, revoked: true},
+		{name: "leave with nothing owned", err: context.Canceled},
+		{name: "fatal loses", err: kerr.IllegalGeneration, assigned: owned, lost: true},
+		{name: "fatal loses in a transact session", txn: true, err: kerr.IllegalGeneration, assigned: owned, lost: true},
+		{name: "fatal without OnPartitionsLost", noLost: true, err: kerr.IllegalGeneration, assigned: owned},
+		{name: "fatal without OnPartitionsLost in a transact session revokes", txn: true, noLost: true, err: kerr.IllegalGeneration, assigned: owned, revoked: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var c calls
+			opts := record(&c)
+			if test.noLost {
+				opts = opts[:1]
+			}
+
+			var cl *Client
+			if test.txn {
+				s, err := NewGroupTransactSession(stubGroupOpts(nil, append(opts, TransactionalID("txn"))...)...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer s.Close()
+				cl = s.Client()
+			} else {
+				var err error
+				if cl, err = NewClient(stubGroupOpts(nil, opts...)...); err != nil {
+					t.Fatal(err)
+				}
+				defer cl.Close()
+			}
+
+			g := cl.consumer.g
+			g.nowAssigned = test.assigned
+			g.endManageSession(test.err)
+
+			if got := len(c.revoked) > 0; got != test.revoked {
+				t.Errorf("got revoked %v (%v), expected %v", got, c.revoked, test.revoked)
+			}
+			if got := len(c.lost) > 0; got != test.lost {
+				t.Errorf("got lost %v (%v), expected %v", got, c.lost, test.lost)
+			}
+			for _, m := range append(c.revoked, c.lost...) {
+				if len(m) != 1 || len(m["t"]) != 2 {
+					t.Errorf("got partitions %v, expected %v", m, owned)
+				}
+			}
+		})
+	}
+}
+
+// recordLogger records the messages and key/values logged at the info level
+// or above.
+type recordLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (*recordLogger) Level() LogLevel { return LogLevelInfo }
+
+func (l *recordLogger) Log(_ LogLevel, msg string, keyvals ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, fmt.Sprint(append([]interface{}{msg}, keyvals...)...))
+}
+
+func TestRevokeAllReason(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		all    revokeAllReason
+		expWhy string
+	}{
+		{"leaving", revokeAllLeaving, "leaving"},
+		{"rejoining", revokeAllRejoining, "fully rejoining"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			l := new(recordLogger)
+			g := newStubGroup(t, nil, Balancers(CooperativeStickyBalancer()), WithLogger(l))
+			g.nowAssigned = map[string][]int32{"t": {0}}
+			g.revoke(revokeThisSession, nil, test.all)
+
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			var revoking []string
+			for _, msg := range l.msgs {
+				if strings.Contains(msg, "revoking all") {
+					revoking = append(revoking, msg)
+				}
+			}
+			if len(revoking) != 2 { // the assign reason and the revoke
+				t.Fatalf("got logs %q revoking all partitions, expected 2", revoking)
+			}
+			for _, msg := range revoking {
+				if !strings.Contains(msg, test.expWhy) || test.all != revokeAllLeaving && strings.Contains(msg, "leaving") {
+					t.Errorf("got log %q, expected it to say %q", msg, test.expWhy)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitCommit(t *testing.T) {
+	type topicParts struct {
+		topic      string
+		partitions []int32
+	}
+	build := func(topics ...topicParts) *kmsg.OffsetCommitRequest {
+		req := kmsg.NewPtrOffsetCommitRequest()
+		req.Group = "group"
+		req.Generation = 3
+		for _, tp := range topics {
+			reqTopic := kmsg.NewOffsetCommitRequestTopic()
+			reqTopic.Topic = tp.topic
+			for _, p := range tp.partitions {
+				reqPartition := kmsg.NewOffsetCommitRequestTopicPartition()
+				reqPartition.Partition = p
+				reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
+			}
+			req.Topics = append(req.Topics, reqTopic)
+		}
+		return req
+	}
+	flatten := func(req *kmsg.OffsetCommitRequest) []topicParts {
+		var tps []topicParts
+		for _, topic := range req.Topics {
+			tp := topicParts{topic: topic.Topic}
+			for _, p := range topic.Partitions {
+				tp.partitions = append(tp.partitions, p.Partition)
+			}
+			tps = append(tps, tp)
+		}
+		return tps
+	}
+
+	for _, test := range []struct {
+		name string
+		max  int
+		in   []topicParts
+		exp  [][]topicParts
+	}{
+		{
+			name: "disabled",
+			max:  0,
+			in:   []topicParts{{"a", []int32{0, 1, 2}}},
+			exp:  [][]topicParts{{{"a", []int32{0, 1, 2}}}},
+		},
+		{
+			name: "fits",
+			max:  3,
+			in:   []topicParts{{"a", []int32{0, 1}}, {"b", []int32{0}}},
+			exp:  [][]topicParts{{{"a", []int32{0, 1}}, {"b", []int32{0}}}},
+		},
+		{
+			name: "topic split across chunks",
+			max:  2,
+			in:   []topicParts{{"a", []int32{0, 1, 2, 3, 4}}},
+			exp: [][]topicParts{
+				{{"a", []int32{0, 1}}},
+				{{"a", []int32{2, 3}}},
+				{{"a", []int32{4}}},
+			},
+		},
+		{
+			name: "topics end on chunk boundary",
+			max:  2,
+			in:   []topicParts{{"a", []int32{0, 1}}, {"b", []int32{0, 1}}},
+			exp: [][]topicParts{
+				{{"a", []int32{0, 1}}},
+				{{"b", []int32{0, 1}}},
+			},
+		},
+		{
+			name: "topics share chunks",
+			max:  3,
+			in:   []topicParts{{"a", []int32{0, 1}}, {"b", []int32{0, 1, 2}}, {"c", []int32{0}}},
+			exp: [][]topicParts{
+				{{"a", []int32{0, 1}}, {"b", []int32{0}}},
+				{{"b", []int32{1, 2}}, {"c", []int32{0}}},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			g := &groupConsumer{cfg: &cfg{commitMaxPartitions: test.max}}
+			req := build(test.in...)
+			chunks := g.splitCommit(req)
+
+			var got [][]topicParts
+			for _, chunk := range chunks {
+				if chunk.Group != req.Group || chunk.Generation != req.Generation {
+					t.Errorf("chunk lost request fields: group %q generation %d", chunk.Group, chunk.Generation)
+				}
+				got = append(got, flatten(chunk))
+			}
+			if !reflect.DeepEqual(got, test.exp) {
+				t.Errorf("got chunks %v, expected %v", got, test.exp)
+			}
+			if exp := flatten(build(test.in...)); !reflect.DeepEqual(flatten(req), exp) {
+				t.Errorf("input request modified: got %v, expected %v", flatten(req), exp)
+			}
+		})
+	}
+}
+
+func TestMergeCommitResps(t *testing.T) {
+	resp := func(throttle int32, topics ...string) *kmsg.OffsetCommitResponse {
+		r := kmsg.NewPtrOffsetCommitResponse()
+		r.ThrottleMillis = throttle
+		for i := 0; i < len(topics); i += 2 {
+			respTopic := kmsg.NewOffsetCommitResponseTopic()
+			respTopic.Topic = topics[i]
+			respPartition := kmsg.NewOffsetCommitResponseTopicPartition()
+			respPartition.Partition = int32(topics[i+1][0] - '0')
+			respTopic.Partitions = append(respTopic.Partitions, respPartition)
+			r.Topics = append(r.Topics, respTopic)
+		}
+		return r
+	}
+	flatten := func(r *kmsg.OffsetCommitResponse) map[string][]int32 {
+		m := make(map[string][]int32)
+		for _, topic := range r.Topics {
+			for _, p := range topic.Partitions {
+				m[topic.Topic] = append(m[topic.Topic], p.Partition)
+			}
+		}
+		return m
+	}
+
+	for _, test := range []struct {
+		name        string
+		resps       []*kmsg.OffsetCommitResponse
+		exp         map[string][]int32
+		expThrottle int32
+	}{
+		{
+			name:        "single",
+			resps:       []*kmsg.OffsetCommitResponse{resp(5, "a", "0")},
+			exp:         map[string][]int32{"a": {0}},
+			expThrottle: 5,
+		},
+		{
+			name:        "same topic across chunks",
+			resps:       []*kmsg.OffsetCommitResponse{resp(1, "a", "0"), resp(9, "a", "1"), resp(3, "a", "2")},
+			exp:         map[string][]int32{"a": {0, 1, 2}},
+			expThrottle: 9,
+		},
+		{
+			name:        "distinct topics",
+			resps:       []*kmsg.OffsetCommitResponse{resp(0, "a", "0"), resp(0, "b", "0", "c", "1")},
+			exp:         map[string][]int32{"a": {0}, "b": {0}, "c": {1}},
+			expThrottle: 0,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var merged *kmsg.OffsetCommitResponse
+			for _, r := range test.resps {
+				merged = mergeCommitResps(merged, r)
+			}
+			if got := flatten(merged); !reflect.DeepEqual(got, test.exp) {
+				t.Errorf("got %v, expected %v", got, test.exp)
+			}
+			if merged.ThrottleMillis != test.expThrottle {
+				t.Errorf("got throttle %d, expected %d", merged.ThrottleMillis, test.expThrottle)
+			}
+		})
+	}
+}
+
+func TestSplitCommitPartialFailure(t *testing.T) {
+	var commits int
+	g := newStubGroup(t, func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+		commit, ok := req.(*kmsg.OffsetCommitRequest)
+		if !ok {
+			return nil, nil
+		}
+		if commits++; commits > 1 {
+			return nil, kerr.RequestTimedOut
+		}
+		return okCommitResponse(commit), nil
+	}, CommitMaxPartitionsPerRequest(1))
+	g.uncommitted = uncommitted{"t": {
+		0: {committed: EpochOffset{-1, -1}},
+		1: {committed: EpochOffset{-1, -1}},
+	}}
+
+	resp, err := g.commitSync(map[string]map[int32]EpochOffset{"t": {0: {-1, 10}, 1: {-1, 20}}})
+	if err != kerr.RequestTimedOut {
+		t.Errorf("got err %v, expected %v", err, kerr.RequestTimedOut)
+	}
+	if resp == nil || len(resp.Topics) != 1 || len(resp.Topics[0].Partitions) != 1 {
+		t.Fatalf("got resp %v, expected the first chunk's response", resp)
+	}
+	committed := resp.Topics[0].Partitions[0].Partition
+	if got := g.uncommitted["t"][committed].committed.Offset; got < 0 {
+		t.Errorf("partition %d in the response was not marked committed", committed)
+	}
+}
+
+func TestCheckCommitRewinds(t *testing.T) {
+	g := newStubGroup(t, nil)
+	g.uncommitted = uncommitted{"t": {
+		0: {committed: EpochOffset{Epoch: 1, Offset: 10}},
+		1: {committed: EpochOffset{Epoch: -1, Offset: -1}},
+	}}
+
+	for _, test := range []struct {
+		name    string
+		disable bool
+		commit  map[string]map[int32]EpochOffset
+		exp     map[string]map[int32]EpochOffset // expected rewinds, nil if none
+	}{
+		{
+			name:   "forward",
+			commit: map[string]map[int32]EpochOffset{"t": {0: {1, 11}, 1: {1, 0}}},
+		},
+		{
+			name:   "same offset",
+			commit: map[string]map[int32]EpochOffset{"t": {0: {1, 10}}},
+		},
+		{
+			name:   "unknown partitions",
+			commit: map[string]map[int32]EpochOffset{"t": {2: {1, 0}}, "u": {0: {1, 0}}},
+		},
+		{
+			name:   "rewind",
+			commit: map[string]map[int32]EpochOffset{"t": {0: {1, 9}, 1: {1, 5}}},
+			exp:    map[string]map[int32]EpochOffset{"t": {0: {1, 9}}},
+		},
+		{
+			name:    "disabled",
+			disable: true,
+			commit:  map[string]map[int32]EpochOffset{"t": {0: {1, 9}}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			g.cfg.rejectCommitRewinds = !test.disable
+			err := g.checkCommitRewinds(test.commit)
+			if test.exp == nil {
+				if err != nil {
+					t.Fatalf("unexpected err: %v", err)
+				}
+				return
+			}
+			var rewind *ErrCommitRewind
+			if !errors.As(err, &rewind) {
+				t.Fatalf("got err %v, expected *ErrCommitRewind", err)
+			}
+			if !reflect.DeepEqual(rewind.Rewinds, test.exp) {
+				t.Errorf("got rewinds %v, expected %v", rewind.Rewinds, test.exp)
+			}
+		})
+	}
+}
+
+// stubListOffsets returns a ListOffsets response with the offset that fn
+// returns for each requested partition, skipping partitions for which fn
+// returns false. Every partition has leader epoch 1.
+func stubListOffsets(req *kmsg.ListOffsetsRequest, fn func(topic string, partition int32, timestamp int64) (int64, bool)) *kmsg.ListOffsetsResponse {
+	resp := req.ResponseKind().(*kmsg.ListOffsetsResponse)
+	for _, reqTopic := range req.Topics {
+		respTopic := kmsg.NewListOffsetsResponseTopic()
+		respTopic.Topic = reqTopic.Topic
+		for _, reqPartition := range reqTopic.Partitions {
+			offset, ok := fn(reqTopic.Topic, reqPartition.Partition, reqPartition.Timestamp)
+			if !ok {
+				continue
+			}
+			respPartition := kmsg.NewListOffsetsResponseTopicPartition()
+			respPartition.Partition = reqPartition.Partition
+			respPartition.LeaderEpoch = 1
+			respPartition.Offset = offset
+			respTopic.Partitions = append(respTopic.Partitions, respPartition)
+		}
+		resp.Topics = append(resp.Topics, respTopic)
+	}
+	return resp
+}
+
+func TestCommitResetToEarliestMissingPartition(t *testing.T) {
+	var commits int
+	g := newStubGroup(t, func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+		switch req := req.(type) {
+		case *kmsg.ListOffsetsRequest:
+			return stubListOffsets(req, func(_ string, partition int32, _ int64) (int64, bool) {
+				return 0, partition == 0
+			}), nil
+		case *kmsg.OffsetCommitRequest:
+			commits++
+			return okCommitResponse(req), nil
+		}
+		return nil, nil
+	})
+
+	err := g.cl.CommitResetToEarliest(context.Background(), map[string][]int32{"t": {0, 1}})
+	if !errors.Is(err, kerr.UnknownTopicOrPartition) {
+		t.Errorf("got err %v, expected %v", err, kerr.UnknownTopicOrPartition)
+	}
+	if commits != 0 {
+		t.Errorf("got %d commits, expected none", commits)
+	}
+}
+
+func TestSeekCommittedBy(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		seek func(*Client) error
+		exp  map[int32]int64 // offsets after seeking
+	}{
+		{
+			name: "rewind",
+			seek: func(cl *Client) error { return cl.RewindBy(context.Background(), 20) },
+			exp:  map[int32]int64{0: 5, 1: 30},
+		},
+		{
+			name: "advance",
+			seek: func(cl *Client) error { return cl.AdvanceBy(context.Background(), 60) },
+			exp:  map[int32]int64{0: 70, 1: 100},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// Every partition's log spans [5, 100).
+			listed := make(map[int64][]int32)
+			g := newStubGroup(t, func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+				list, ok := req.(*kmsg.ListOffsetsRequest)
+				if !ok {
+					return nil, nil
+				}
+				return stubListOffsets(list, func(_ string, partition int32, timestamp int64) (int64, bool) {
+					listed[timestamp] = append(listed[timestamp], partition)
+					if timestamp == -2 {
+						return 5, true
+					}
+					return 100, true
+				}), nil
+			})
+			g.mu.Lock()
+			g.uncommitted = uncommitted{"t": {
+				0: {dirty: EpochOffset{1, 10}, head: EpochOffset{1, 10}, committed: EpochOffset{1, 10}},
+				1: {dirty: EpochOffset{1, 50}, head: EpochOffset{1, 50}, committed: EpochOffset{1, 50}},
+				2: {dirty: EpochOffset{1, 8}, head: EpochOffset{1, 8}, committed: EpochOffset{-1, -1}},
+			}}
+			g.mu.Unlock()
+
+			if err := test.seek(g.cl); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, timestamp := range []int64{-2, -1} {
+				sort.Slice(listed[timestamp], func(i, j int) bool { return listed[timestamp][i] < listed[timestamp][j] })
+				if exp := []int32{0, 1}; !reflect.DeepEqual(listed[timestamp], exp) {
+					t.Errorf("listed offsets at %d for partitions %v, expected %v", timestamp, listed[timestamp], exp)
+				}
+			}
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			for partition, offset := range test.exp {
+				if got, exp := g.uncommitted["t"][partition].head, (EpochOffset{-1, offset}); got != exp {
+					t.Errorf("partition %d at %v after seeking, expected %v", partition, got, exp)
+				}
+			}
+			if got, exp := g.uncommitted["t"][2].head, (EpochOffset{1, 8}); got != exp {
+				t.Errorf("uncommitted partition 2 moved to %v, expected to stay at %v", got, exp)
+			}
+		})
+	}
+}
+
+func TestCommitResetToEarliestSkipsRewindCheck(t *testing.T) {
+	var sent []*kmsg.OffsetCommitRequest
+	g := newStubGroup(t, func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+		switch req := req.(type) {
+		case *kmsg.ListOffsetsRequest:
+			return stubListOffsets(req, func(string, int32, int64) (int64, bool) { return 0, true }), nil
+		case *kmsg.OffsetCommitRequest:
+			sent = append(sent, req)
+			return okCommitResponse(req), nil
+		}
+		return nil, nil
+	}, RejectCommitRewinds())
+	g.uncommitted = uncommitted{"t": {0: {
+		head:      EpochOffset{Epoch: 1, Offset: 10},
+		committed: EpochOffset{Epoch: 1, Offset: 10},
+	}}}
+
+	var rewind *ErrCommitRewind
+	if _, err := g.cl.CommitOffsetsSyncResult(context.Background(), map[string]map[int32]EpochOffset{"t": {0: {1, 0}}}); !errors.As(err, &rewind) {
+		t.Fatalf("got err %v from rewinding CommitOffsetsSync, expected *ErrCommitRewind", err)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("rejected rewind issued %d commits", len(sent))
+	}
+
+	if err := g.cl.CommitResetToEarliest(context.Background(), map[string][]int32{"t": {0}}); err != nil {
+		t.Fatalf("unexpected reset err: %v", err)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("got %d commits, expected 1", len(sent))
+	}
+	if got := sent[0].Topics[0].Partitions[0].Offset; got != 0 {
+		t.Errorf("reset committed offset %d, expected 0", got)
+	}
+	if got, exp := g.uncommitted["t"][0].committed, (EpochOffset{Epoch: 1, Offset: 0}); got != exp {
+		t.Errorf("got committed %v, expected %v", got, exp)
+	}
+}
+
+func TestHeartbeatCooperativeFastCheck(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		cooperative bool
+		exp         time.Duration // when the first heartbeat is issued
+	}{
+		{"cooperative", true, 500 * time.Millisecond},
+		{"eager", false, 3 * time.Second},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			clock := newFakeClock()
+			beats := make(chan time.Time, 1)
+			g := newStubGroup(t, func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+				if _, ok := req.(*kmsg.HeartbeatRequest); !ok {
+					return nil, nil
+				}
+				beats <- clock.Now()
+				resp := kmsg.NewPtrHeartbeatResponse()
+				resp.ErrorCode = kerr.UnknownMemberID.Code // quits the loop
+				return resp, nil
+			}, withClock(clock), HeartbeatInterval(3*time.Second), DisableAutoCommit())
+			g.cooperative = test.cooperative
+
+			start := clock.Now()
+			done := make(chan error, 1)
+			go func() { done <- g.heartbeatLoop(nil, newAssignRevokeSession()) }()
+
+			pending := 1 // the heartbeat ticker
+			if test.cooperative {
+				pending++ // the fast check
+			}
+			clock.waitPending(pending)
+			clock.Advance(500 * time.Millisecond)
+			if !test.cooperative {
+				clock.Advance(2500 * time.Millisecond)
+			}
+
+			if got := (<-beats).Sub(start); got != test.exp {
+				t.Errorf("first heartbeat after %v, expected %v", got, test.exp)
+			}
+			if err := <-done; err != kerr.UnknownMemberID {
+				t.Errorf("got heartbeat loop err %v, expected %v", err, kerr.UnknownMemberID)
+			}
+		})
+	}
+}
+
+func TestAutocommitCadence(t *testing.T) {
+	clock := newFakeClock()
+	var sent []*kmsg.OffsetCommitRequest
+	committed := make(chan struct{})
+	g := newStubGroup(t, func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+		commit, ok := req.(*kmsg.OffsetCommitRequest)
+		if !ok {
+			return nil, nil
+		}
+		sent = append(sent, commit)
+		return okCommitResponse(commit), nil
+	},
+		withClock(clock),
+		AutoCommitInterval(2*time.Second),
+		AutoCommitTopicIntervals(map[string]time.Duration{"fast": time.Second}),
+		AutoCommitCallback(func(*Client, *kmsg.OffsetCommitRequest, *kmsg.OffsetCommitResponse, error) {
+			committed <- struct{}{}
+		}),
+	)
+
+	clock.waitPending(1) // the commit ticker; the client starts autocommitting
+
+	var got [][]string
+	for i := int64(1); i <= 4; i++ {
+		g.mu.Lock()
+		g.uncommitted = make(uncommitted)
+		for _, topic := range []string{"fast", "slow"} {
+			head := EpochOffset{Epoch: 0, Offset: i}
+			g.uncommitted[topic] = map[int32]uncommit{0: {
+				head:      head,
+				dirty:     head,
+				committed: EpochOffset{Epoch: 0, Offset: i - 1},
+			}}
+		}
+		g.mu.Unlock()
+
+		clock.Advance(time.Second)
+		<-committed
+
+		var topics []string
+		for _, topic := range sent[len(sent)-1].Topics {
+			topics = append(topics, topic.Topic)
+		}
+		sort.Strings(topics)
+		got = append(got, topics)
+	}
+
+	exp := [][]string{
+		{"fast", "slow"},
+		{"fast"},
+		{"fast", "slow"},
+		{"fast"},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("got committed topics %v, expected %v", got, exp)
+	}
+}
+
+func TestCommitOffsetsQueuedCanceledWhileFull(t *testing.T) {
+	g := newStubGroup(t, nil)
+	for i := 0; i < cap(g.commitQueueSem); i++ {
+		g.commitQueueSem <- struct{}{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var called bool
+	g.cl.CommitOffsetsQueued(ctx, map[string]map[int32]EpochOffset{"t": {0: {0, 1}}}, func(_ *Client, req *kmsg.OffsetCommitRequest, resp *kmsg.OffsetCommitResponse, err error) {
+		called = true
+		if req == nil || resp == nil {
+			t.Errorf("got nil request %v or response %v", req == nil, resp == nil)
+		}
+		if err != context.Canceled {
+			t.Errorf("got err %v, expected %v", err, context.Canceled)
+		}
+	})
+	if !called {
+		t.Error("onDone was not called")
+	}
+}
+
+func TestListGroupOffsetsUsesGroupRequireStable(t *testing.T) {
+	var stable []bool
+	g := newStubGroup(t, func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+		fetch, ok := req.(*kmsg.OffsetFetchRequest)
+		if !ok {
+			return nil, nil
+		}
+		stable = append(stable, fetch.RequireStable)
+		resp := kmsg.NewPtrOffsetFetchResponse()
+		respGroup := kmsg.NewOffsetFetchResponseGroup()
+		respGroup.Group = fetch.Group
+		resp.Groups = append(resp.Groups, respGroup)
+		return resp, nil
+	}, RequireStableFetchOffsets())
+
+	if _, err := g.cl.ListGroupOffsets(context.Background(), "other"); err != nil {
+		t.Fatal(err)
+	}
+	g.cl.SetRequireStable(false)
+	if _, err := g.cl.ListGroupOffsets(context.Background(), "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := []bool{true, false}; !reflect.DeepEqual(stable, exp) {
+		t.Errorf("got require stable %v, expected %v", stable, exp)
+	}
+}
+
+func TestAutoCommitSkipUnchanged(t *testing.T) {
+	// "stalled" has records polled (dirty) that have not yet moved to
+	// head, meaning head is what is committed; "progressed" has a head
+	// past what is committed; "idle" has nothing new polled at all.
+	g := &groupConsumer{cfg: new(cfg), uncommitted: uncommitted{
+		"stalled":    {0: {dirty: EpochOffset{0, 5}, head: EpochOffset{0, 3}, committed: EpochOffset{0, 3}}},
+		"progressed": {0: {dirty: EpochOffset{0, 7}, head: EpochOffset{0, 7}, committed: EpochOffset{0, 3}}},
+		"idle":       {0: {dirty: EpochOffset{0, 3}, head: EpochOffset{0, 3}, committed: EpochOffset{0, 3}}},
+	}}
+
+	for _, test := range []struct {
+		name string
+		skip bool
+		exp  map[string]map[int32]EpochOffset
+	}{
+		{
+			name: "default",
+			exp: map[string]map[int32]EpochOffset{
+				"stalled":    {0: {0, 3}},
+				"progressed": {0: {0, 7}},
+			},
+		},
+		{
+			name: "skip unchanged",
+			skip: true,
+			exp: map[string]map[int32]EpochOffset{
+				"progressed": {0: {0, 7}},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// This mirrors what loopCommit commits.
+			got := g.getUncommittedLocked(true, false)
+			if test.skip {
+				g.dropUnchangedLocked(got)
+			}
+			if !reflect.DeepEqual(got, test.exp) {
+				t.Errorf("got autocommit %v, expected %v", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestPausedTopicsUncommitted(t *testing.T) {
+	polled := EpochOffset{1, 10}
+	for _, test := range []struct {
+		name       string
+		opt        Opt
+		pauseFirst bool // pause before polling rather than after
+	}{
+		{"autocommitting, polled before pausing", AutoCommitInterval(time.Hour), false},
+		{"autocommitting, polled while paused", AutoCommitInterval(time.Hour), true},
+		{"autocommit disabled, polled while paused", DisableAutoCommit(), true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			g := newStubGroup(t, nil, test.opt)
+			committed := EpochOffset{1, 5}
+			g.mu.Lock()
+			g.uncommitted = uncommitted{
+				"paused":  {0: {dirty: committed, head: committed, committed: committed}},
+				"running": {0: {dirty: committed, head: committed, committed: committed}},
+			}
+			g.mu.Unlock()
+
+			pause := func() {
+				g.mu.Lock()
+				g.pausedTopics = map[string]struct{}{"paused": {}}
+				g.mu.Unlock()
+			}
+			if test.pauseFirst {
+				pause()
+			}
+			var fetch Fetch
+			for _, topic := range []string{"paused", "running"} {
+				fetch.Topics = append(fetch.Topics, FetchTopic{Topic: topic, Partitions: []FetchPartition{{
+					Partition: 0,
+					Records:   []*Record{{Topic: topic, LeaderEpoch: polled.Epoch, Offset: polled.Offset - 1}},
+				}}})
+			}
+			g.updateUncommitted(Fetches{fetch})
+			if !test.pauseFirst {
+				pause()
+			}
+			g.undirtyUncommitted() // as the next poll does
+
+			committable := func() map[string]map[int32]EpochOffset {
+				g.mu.Lock()
+				defer g.mu.Unlock()
+				got := g.getUncommittedLocked(true, false)
+				g.dropUnchangedLocked(got)
+				return got
+			}
+			if got, exp := committable(), map[string]map[int32]EpochOffset{"running": {0: polled}}; !reflect.DeepEqual(got, exp) {
+				t.Errorf("got committable %v while paused, expected %v", got, exp)
+			}
+
+			g.cl.ResumeTopics("paused")
+			g.updateUncommitted(Fetches{fetch}) // the records are fetched and polled again
+			g.undirtyUncommitted()
+			if got, exp := committable(), map[string]map[int32]EpochOffset{"paused": {0: polled}, "running": {0: polled}}; !reflect.DeepEqual(got, exp) {
+				t.Errorf("got committable %v after resuming, expected %v", got, exp)
+			}
+		})
+	}
+}
+
+func TestGroupMemberCountDescribesLazily(t *testing.T) {
+	var describes int
+	states := []string{"PreparingRebalance", "Stable"}
+	g := newStubGroup(t, func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+		if _, ok := req.(*kmsg.DescribeGroupsRequest); !ok {
+			return nil, nil
+		}
+		resp := kmsg.NewPtrDescribeGroupsResponse()
+		group := kmsg.NewDescribeGroupsResponseGroup()
+		group.Group = "group"
+		group.State = states[describes]
+		for i := 0; i < 3; i++ {
+			group.Members = append(group.Members, kmsg.NewDescribeGroupsResponseGroupMember())
+		}
+		resp.Groups = append(resp.Groups, group)
+		describes++
+		return resp, nil
+	})
+	g.mu.Lock()
+	g.memberID = "member"
+	g.generation = 2
+	g.mu.Unlock()
+
+	// count calls GroupMemberCount and waits for any describe it issues.
+	count := func() (int, bool) {
+		n, ok := g.cl.GroupMemberCount()
+		g.mu.Lock()
+		done := g.memberCountDone
+		g.mu.Unlock()
+		if done != nil {
+			<-done
+		}
+		return n, ok
+	}
+
+	if _, ok := count(); ok {
+		t.Error("count known before describing")
+	}
+	if _, ok := count(); ok {
+		t.Error("count cached from a group that is not stable")
+	}
+	if n, ok := count(); !ok || n != 3 {
+		t.Errorf("got count %d (known? %v), expected 3", n, ok)
+	}
+	if n, ok := count(); !ok || n != 3 {
+		t.Errorf("got cached count %d (known? %v), expected 3", n, ok)
+	}
+	if describes != 2 {
+		t.Errorf("got %d describes, expected 2", describes)
+	}
+}
+
+func TestRevokeCommitCallbackWithoutAutocommit(t *testing.T) {
+	var called bool
+	g := newStubGroup(t, func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+		if commit, ok := req.(*kmsg.OffsetCommitRequest); ok {
+			return okCommitResponse(commit), nil
+		}
+		return nil, nil
+	},
+		DisableAutoCommit(),
+		AutoCommitCallback(func(_ *Client, _ *kmsg.OffsetCommitRequest, _ *kmsg.OffsetCommitResponse, err error) {
+			called = true
+			if err != nil {
+				t.Errorf("unexpected commit err: %v", err)
+			}
+		}),
+		OnPartitionsRevokedCommit(func(context.Context, *Client, map[string][]int32) map[string]map[int32]EpochOffset {
+			return map[string]map[int32]EpochOffset{"t": {0: {0, 10}}}
+		}),
+	)
+
+	g.revokeCommit(context.Background(), g.cl, map[string][]int32{"t": {0}})
+	if !called {
+		t.Error("the revoke commit did not call the AutoCommitCallback")
+	}
+}
+
+func TestSeekToCommittedFetchesAllAssigned(t *testing.T) {
+	var fetched map[string][]int32
+	g := newStubGroup(t, func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+		fetch, ok := req.(*kmsg.OffsetFetchRequest)
+		if !ok {
+			return nil, nil
+		}
+		fetched = make(map[string][]int32)
+		resp := fetch.ResponseKind().(*kmsg.OffsetFetchResponse)
+		for _, reqTopic := range fetch.Topics {
+			fetched[reqTopic.Topic] = append([]int32(nil), reqTopic.Partitions...)
+			sort.Slice(fetched[reqTopic.Topic], func(i, j int) bool { return fetched[reqTopic.Topic][i] < fetched[reqTopic.Topic][j] })
+			respTopic := kmsg.NewOffsetFetchResponseTopic()
+			respTopic.Topic = reqTopic.Topic
+			for _, partition := range reqTopic.Partitions {
+				respPartition := kmsg.NewOffsetFetchResponseTopicPartition()
+				respPartition.Partition = partition
+				respPartition.Offset = 20
+				respTopic.Partitions = append(respTopic.Partitions, respPartition)
+			}
+			resp.Topics = append(resp.Topics, respTopic)
+		}
+		return resp, nil
+	})
+	g.mu.Lock()
+	g.nowAssigned = map[string][]int32{"t": {0, 1}, "excluded": {0}}
+	g.using = map[string]int{"t": 2, "excluded": 1}
+	g.excluded = map[string]struct{}{"excluded": {}}
+	defer func() {
+		g.mu.Lock()
+		g.using = make(map[string]int) // the group is not managed, so Close must not wait for it
+		g.mu.Unlock()
+	}()
+	// Partition 1 had no committed offset at join and has not been
+	// polled, so it is not yet tracked.
+	g.uncommitted = uncommitted{"t": {0: {
+		dirty:     EpochOffset{-1, 5},
+		head:      EpochOffset{-1, 5},
+		committed: EpochOffset{-1, 5},
+	}}}
+	g.mu.Unlock()
+
+	if err := g.cl.SeekToCommitted(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := map[string][]int32{"t": {0, 1}}; !reflect.DeepEqual(fetched, exp) {
+		t.Errorf("fetched committed offsets for %v, expected %v", fetched, exp)
+	}
+	for _, partition := range []int32{0, 1} {
+		if got := g.uncommitted["t"][partition].committed.Offset; got != 20 {
+			t.Errorf("partition %d committed offset %d after seeking, expected 20", partition, got)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package kgo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+func TestLookupOffsetEpochs(t *testing.T) {
+	// The log for t[0] has epoch 0 at offset 0, epoch 2 at offset 10, and
+	// epoch 5 at offset 25. OffsetForLeaderEpoch answers with the largest
+	// epoch at or before the requested one and where that epoch ends.
+	endOf := map[int32]struct {
+		epoch int32
+		end   int64
+	}{
+		4: {2, 25},
+		3: {2, 25},
+		2: {2, 25},
+		1: {0, 10},
+	}
+
+	for _, test := range []struct {
+		name       string
+		noEpochs   bool  // brokers do not support OffsetForLeaderEpoch v2
+		metaEpoch  int32 // current leader epoch in metadata
+		offset     int64
+		errCode    int16 // OffsetForLeaderEpoch partition error
+		misbehave  bool  // OffsetForLeaderEpoch echoes the requested epoch
+		exp        EpochOffset
+		expLookups []int32 // epochs requested in OffsetForLeaderEpoch
+		expErr     error
+	}{
+		{
+			name:       "in the current epoch",
+			metaEpoch:  5,
+			offset:     30,
+			exp:        EpochOffset{Epoch: 5, Offset: 30},
+			expLookups: []int32{4},
+		},
+		{
+			name:       "at the start of the current epoch",
+			metaEpoch:  5,
+			offset:     25,
+			exp:        EpochOffset{Epoch: 5, Offset: 25},
+			expLookups: []int32{4},
+		},
+		{
+			name:       "walking back one epoch",
+			metaEpoch:  5,
+			offset:     12,
+			exp:        EpochOffset{Epoch: 2, Offset: 12},
+			expLookups: []int32{4, 1},
+		},
+		{
+			name:       "walking back two epochs",
+			metaEpoch:  5,
+			offset:     5,
+			exp:        EpochOffset{Epoch: 0, Offset: 5},
+			expLookups: []int32{4, 1},
+		},
+		{
+			name:      "epoch 0",
+			metaEpoch: 0,
+			offset:    5,
+			exp:       EpochOffset{Epoch: 0, Offset: 5},
+		},
+		{
+			name:      "old broker without leader epochs",
+			metaEpoch: -1,
+			offset:    5,
+			exp:       EpochOffset{Epoch: -1, Offset: 5},
+		},
+		{
+			name:      "old broker without OffsetForLeaderEpoch",
+			noEpochs:  true,
+			metaEpoch: 5,
+			offset:    5,
+			exp:       EpochOffset{Epoch: -1, Offset: 5},
+		},
+		{
+			name:       "misbehaving broker",
+			metaEpoch:  5,
+			offset:     5,
+			misbehave:  true,
+			exp:        EpochOffset{Epoch: 5, Offset: 5},
+			expLookups: []int32{4},
+		},
+		{
+			name:       "partition error",
+			metaEpoch:  5,
+			offset:     5,
+			errCode:    kerr.NotLeaderForPartition.Code,
+			expLookups: []int32{4},
+			expErr:     kerr.NotLeaderForPartition,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var lookups []int32
+			cl, err := NewClient(
+				SeedBrokers("127.0.0.1:1"),
+				InterceptRequests(func(_ context.Context, req kmsg.Request) (kmsg.Response, error) {
+					switch req := req.(type) {
+					case *kmsg.MetadataRequest:
+						resp := kmsg.NewPtrMetadataResponse()
+						for _, reqTopic := range req.Topics {
+							respTopic := kmsg.NewMetadataResponseTopic()
+							respTopic.Topic = reqTopic.Topic
+							respPartition := kmsg.NewMetadataResponseTopicPartition()
+							respPartition.LeaderEpoch = test.metaEpoch
+							respTopic.Partitions = append(respTopic.Partitions, respPartition)
+							resp.Topics = append(resp.Topics, respTopic)
+						}
+						return resp, nil
+
+					case *kmsg.OffsetForLeaderEpochRequest:
+						resp := kmsg.NewPtrOffsetForLeaderEpochResponse()
+						for _, reqTopic := range req.Topics {
+							respTopic := kmsg.NewOffsetForLeaderEpochResponseTopic()
+							respTopic.Topic = reqTopic.Topic
+							for _, reqPartition := range reqTopic.Partitions {
+								lookups = append(lookups, reqPartition.LeaderEpoch)
+								respPartition := kmsg.NewOffsetForLeaderEpochResponseTopicPartition()
+								respPartition.Partition = reqPartition.Partition
+								respPartition.ErrorCode = test.errCode
+								end := endOf[reqPartition.LeaderEpoch]
+								respPartition.LeaderEpoch, respPartition.EndOffset = end.epoch, end.end
+								if test.misbehave {
+									respPartition.LeaderEpoch = reqPartition.LeaderEpoch + 1
+								}
+								respTopic.Partitions = append(respTopic.Partitions, respPartition)
+							}
+							resp.Topics = append(resp.Topics, respTopic)
+						}
+						return resp, nil
+					}
+					return nil, nil
+				}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cl.Close()
+
+			if !test.noEpochs {
+				b := cl.newBroker(1, "127.0.0.1", 1, nil)
+				v := newBrokerVersions()
+				v.versions[23] = 2
+				b.storeVersions(v)
+				cl.brokersMu.Lock()
+				cl.brokers = append(cl.brokers, b)
+				cl.brokersMu.Unlock()
+			}
+
+			got, err := cl.lookupOffsetEpochs(context.Background(), map[string]map[int32]int64{"t": {0: test.offset}})
+			if !reflect.DeepEqual(lookups, test.expLookups) {
+				t.Errorf("got OffsetForLeaderEpoch lookups %v, exp %v", lookups, test.expLookups)
+			}
+			if test.expErr != nil {
+				if !errors.Is(err, test.expErr) {
+					t.Fatalf("got err %v, exp %v", err, test.expErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if exp := map[string]map[int32]EpochOffset{"t": {0: test.exp}}; !reflect.DeepEqual(got, exp) {
+				t.Errorf("got %v, exp %v", got, exp)
+			}
+		})
+	}
+}
+
+func TestPauseTopicsSavesCursors(t *testing.T) {
+	g := newStubGroup(t, nil)
+	c := g.c
+
+	// t has two partitions: we are consuming partition 0, and partition 1
+	// is owned by another member.
+	tp := g.tps.load()["t"]
+	prior := tp.load()
+	tp.v.Store(&topicPartitionsData{partitions: make([]*topicPartition, 2)})
+	defer tp.v.Store(prior)
+
+	consuming := &cursor{topic: "t", partition: 0, useState: 1, cursorOffset: cursorOffset{offset: 7, lastConsumedEpoch: 2}}
+	other := &cursor{topic: "u", partition: 0, useState: 1, cursorOffset: cursorOffset{offset: 3, lastConsumedEpoch: 1}}
+	c.mu.Lock()
+	c.usingCursors.use(consuming)
+	c.usingCursors.use(other)
+	c.mu.Unlock()
+
+	g.cl.PauseTopics("t")
+
+	c.mu.Lock()
+	if _, using := c.usingCursors[consuming]; using || consuming.usable() || consuming.offset != -1 {
+		t.Errorf("paused cursor is still assigned at %d", consuming.offset)
+	}
+	if _, using := c.usingCursors[other]; !using || other.offset != 3 {
+		t.Errorf("cursor for an unpaused topic was unassigned or moved to %d", other.offset)
+	}
+	if exp := map[string]map[int32]Offset{"t": {0: {at: 7, epoch: 2}}}; !reflect.DeepEqual(c.pausedOffsets, exp) {
+		t.Errorf("got paused offsets %v, expected %v", c.pausedOffsets, exp)
+	}
+	c.mu.Unlock()
+
+	// Pausing again does not lose where we were, and setting an offset
+	// while paused is where we resume from.
+	g.cl.PauseTopics("t")
+	g.cl.SetOffsets(map[string]map[int32]EpochOffset{"t": {0: {Epoch: 3, Offset: 20}}})
+	c.mu.Lock()
+	if exp := map[string]map[int32]Offset{"t": {0: {at: 20, epoch: 3}}}; !reflect.DeepEqual(c.pausedOffsets, exp) {
+		t.Errorf("got paused offsets %v after setting, expected %v", c.pausedOffsets, exp)
+	}
+	c.mu.Unlock()
+
+	g.cl.ResumeTopics("t")
+	c.mu.Lock()
+	if len(c.pausedOffsets) != 0 {
+		t.Errorf("got paused offsets %v after resuming, expected none", c.pausedOffsets)
+	}
+	c.mu.Unlock()
+	if paused := g.cl.PauseFetchTopics(); len(paused) != 0 {
+		t.Errorf("got paused fetch topics %v after resuming, expected none", paused)
+	}
+}
@@ -132,6 +132,22 @@ var (
 	// assigned a group.
 	errNotGroup = errors.New("invalid group function call when not assigned a group")
 
+	// Returned when waiting on a group to be stable after the group has
+	// been left or the client has been closed.
+	errGroupLeft = errors.New("group consumer has left the group")
+
+	// Returned from joining and syncing if the group rebalance did not
+	// complete within LeaveOnStuckRebalance.
+	errRebalanceStuck = errors.New("group rebalance did not complete in time, left the group to rejoin with a new member id")
+
+	// Returned from CommitUntilDrained if a successful commit did not
+	// change what remains to be committed.
+	errCommitNoProgress = errors.New("commit succeeded but the same offsets remain uncommitted, stopping draining")
+
+	// Returned from ExcludeGroupTopics if every consumed topic would be
+	// excluded.
+	errExcludeAllGroupTopics = errors.New("cannot exclude every topic the group is consuming")
+
 	// Returned when trying to begin a transaction with a client that does
 	// not have a transactional ID.
 	errNotTransactional = errors.New("invalid attempt to begin a transaction with a non-transactional client")
@@ -192,6 +208,39 @@ func (e *ErrDataLoss) Error() string {
 		e.Topic, e.Partition, e.ConsumedTo, e.ResetTo)
 }
 
+// ErrCommitRewind is passed to a commit's onDone callback if RejectCommitRewinds
+// is enabled and the commit would rewind the committed offset for any
+// partition. No offsets are committed if this is returned.
+type ErrCommitRewind struct {
+	// Rewinds contains each partition that would have been rewound,
+	// mapped to the offset that was requested to be committed.
+	Rewinds map[string]map[int32]EpochOffset
+}
+
+func (e *ErrCommitRewind) Error() string {
+	var n int
+	for _, partitions := range e.Rewinds {
+		n += len(partitions)
+	}
+	return fmt.Sprintf("refusing to commit offsets that would rewind %d partition(s): %v", n, e.Rewinds)
+}
+
+// ErrUnrequestedTopicAssigned is returned from group management if
+// FailOnUnrequestedTopicAssignment is enabled and the group's balancer
+// assigned this member topics that it is not consuming.
+type ErrUnrequestedTopicAssigned struct {
+	// Group is the group the member was assigned in.
+	Group string
+	// Topics are the topics the member was assigned but did not
+	// request.
+	Topics []string
+}
+
+func (e *ErrUnrequestedTopicAssigned) Error() string {
+	return fmt.Sprintf("group %s assigned this member topics that it did not ask for, which indicates a balancer bug: %v",
+		e.Group, e.Topics)
+}
+
 type errUnknownController struct {
 	id int32
 }
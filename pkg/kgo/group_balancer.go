@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 
+	"github.com/twmb/franz-go/pkg/kbin"
 	"github.com/twmb/franz-go/pkg/kerr"
 	"github.com/twmb/franz-go/pkg/kgo/internal/sticky"
 	"github.com/twmb/franz-go/pkg/kmsg"
@@ -48,7 +50,10 @@ type GroupBalancer interface {
 	// The input group members are guaranteed to be sorted first by
 	// instance ID, if non-nil, and then by member ID.
 	//
-	// It is up to the user to decide how to decode each member's
+	// Each member's ProtocolMetadata field is the raw metadata that the
+	// member returned from its JoinGroupMetadata, meaning balancers that
+	// encode custom fields (rack, weight, etc.) can decode their own
+	// encoding. It is up to the user to decide how to decode each member's
 	// ProtocolMetadata field. The default client group protocol of
 	// "consumer" by default uses join group metadata's of type
 	// kmsg.ConsumerMemberMetadata. If this is the case for you, it may be
@@ -63,6 +68,23 @@ type GroupBalancer interface {
 	IsCooperative() bool
 }
 
+// GroupStandbyBalancer is an optional interface a GroupBalancer can implement
+// to assign standby partitions in addition to the partitions returned from
+// ParseSyncAssignment. Standby partitions are not consumed; they are
+// surfaced to HookGroupStandbyAssigned so that state for them can be kept
+// warm, and so that they can be promoted quickly if their active member
+// fails.
+type GroupStandbyBalancer interface {
+	GroupBalancer
+
+	// ParseStandbySyncAssignment returns the standby topics and
+	// partitions from an encoded SyncGroupResponse's MemberAssignment.
+	//
+	// If this returns nil, the member has no standby assignment and
+	// HookGroupStandbyAssigned is not called.
+	ParseStandbySyncAssignment(assignment []byte) (map[string][]int32, error)
+}
+
 // GroupMemberBalancer balances topics amongst group members.
 type GroupMemberBalancer interface {
 	// Balance balances topics and partitions among group members, where
@@ -102,6 +124,9 @@ func (b *ConsumerBalancer) Members() []kmsg.JoinGroupResponseMember {
 
 // EachMember calls fn for each member and its corresponding metadata in the
 // consumer group being balanced.
+//
+// The member's ProtocolMetadata field is the raw, undecoded metadata, which can
+// be used if members encode more than the consumer member metadata.
 func (b *ConsumerBalancer) EachMember(fn func(member *kmsg.JoinGroupResponseMember, meta *kmsg.ConsumerMemberMetadata)) {
 	for i := range b.members {
 		fn(&b.members[i], &b.metadatas[i])
@@ -237,15 +262,7 @@ func (p *BalancePlan) AddPartitions(member *kmsg.JoinGroupResponseMember, topic
 func (p *BalancePlan) IntoSyncAssignment() []kmsg.SyncGroupRequestGroupAssignment {
 	kassignments := make([]kmsg.SyncGroupRequestGroupAssignment, 0, len(p.plan))
 	for member, assignment := range p.plan {
-		var kassignment kmsg.ConsumerMemberAssignment
-		for topic, partitions := range assignment {
-			sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
-			assnTopic := kmsg.NewConsumerMemberAssignmentTopic()
-			assnTopic.Topic = topic
-			assnTopic.Partitions = partitions
-			kassignment.Topics = append(kassignment.Topics, assnTopic)
-		}
-		sort.Slice(kassignment.Topics, func(i, j int) bool { return kassignment.Topics[i].Topic < kassignment.Topics[j].Topic })
+		kassignment := consumerMemberAssignment(assignment)
 		syncAssn := kmsg.NewSyncGroupRequestGroupAssignment()
 		syncAssn.MemberID = member
 		syncAssn.MemberAssignment = kassignment.AppendTo(nil)
@@ -255,6 +272,21 @@ func (p *BalancePlan) IntoSyncAssignment() []kmsg.SyncGroupRequestGroupAssignmen
 	return kassignments
 }
 
+// consumerMemberAssignment returns the assignment as a
+// kmsg.ConsumerMemberAssignment, sorted by topic and partition.
+func consumerMemberAssignment(assignment map[string][]int32) kmsg.ConsumerMemberAssignment {
+	var kassignment kmsg.ConsumerMemberAssignment
+	for topic, partitions := range assignment {
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+		assnTopic := kmsg.NewConsumerMemberAssignmentTopic()
+		assnTopic.Topic = topic
+		assnTopic.Partitions = partitions
+		kassignment.Topics = append(kassignment.Topics, assnTopic)
+	}
+	sort.Slice(kassignment.Topics, func(i, j int) bool { return kassignment.Topics[i].Topic < kassignment.Topics[j].Topic })
+	return kassignment
+}
+
 func joinMemberLess(l, r *kmsg.JoinGroupResponseMember) bool {
 	if l.InstanceID != nil {
 		if r.InstanceID == nil {
@@ -346,9 +378,10 @@ func (g *groupConsumer) balanceGroup(proto string, members []kmsg.JoinGroupRespo
 	// If the returned balancer is a ConsumerBalancer (which it likely
 	// always will be), then we can print some useful debugging information
 	// about what member interests are.
-	if b, ok := memberBalancer.(*ConsumerBalancer); ok {
+	cb, isConsumerBalancer := unwrapConsumerBalancer(memberBalancer)
+	if isConsumerBalancer {
 		interests := new(bytes.Buffer)
-		b.EachMember(func(member *kmsg.JoinGroupResponseMember, meta *kmsg.ConsumerMemberMetadata) {
+		cb.EachMember(func(member *kmsg.JoinGroupResponseMember, meta *kmsg.ConsumerMemberMetadata) {
 			interests.Reset()
 			fmt.Fprintf(interests, "interested topics: %v, previously owned: ", meta.Topics)
 			for _, owned := range meta.OwnedPartitions {
@@ -372,13 +405,67 @@ func (g *groupConsumer) balanceGroup(proto string, members []kmsg.JoinGroupRespo
 	// is if the balancer is a ConsumerBalancer, then we can again print
 	// more useful debugging information.
 	into := memberBalancer.Balance(topicPartitionCount)
-	if p, ok := into.(*BalancePlan); ok {
+	p, ok := into.(*BalancePlan)
+	if asp, isStandby := into.(*activeStandbyPlan); isStandby {
+		p, ok = asp.active, true // standbys do not count towards moves
+		g.cl.cfg.logger.Log(LogLevelInfo, "balanced", "plan", asp.String())
+	} else if ok {
 		g.cl.cfg.logger.Log(LogLevelInfo, "balanced", "plan", p.String())
+	}
+	if ok {
+		if isConsumerBalancer {
+			moved, owned := p.movedFrom(cb)
+			g.cl.cfg.logger.Log(LogLevelInfo, "balance moved partitions", "group", g.cfg.group, "generation", g.generation, "moved", moved, "previously_owned", owned)
+			g.cfg.hooks.each(func(h Hook) {
+				if h, ok := h.(HookGroupPartitionsMoved); ok {
+					h.OnGroupPartitionsMoved(g.cfg.group, g.generation, moved, owned)
+				}
+			})
+		}
 	} else {
 		g.cl.cfg.logger.Log(LogLevelInfo, "unable to log balance plan: the user has returned a custom IntoSyncAssignment (not a *BalancePlan)")
 	}
 
-	return into.IntoSyncAssignment(), nil
+	assignments := into.IntoSyncAssignment()
+	g.setLastBalancePlan(members, assignments)
+	return assignments, nil
+}
+
+// setLastBalancePlan decodes the leader's sync assignments into the plan
+// returned from LastBalancePlan and logs the plan at the debug level.
+// Assignments that are not in the consumer protocol format are skipped.
+func (g *groupConsumer) setLastBalancePlan(members []kmsg.JoinGroupResponseMember, assignments []kmsg.SyncGroupRequestGroupAssignment) {
+	plan := make(map[string]map[string][]int32, len(members))
+	for _, member := range members {
+		plan[member.MemberID] = make(map[string][]int32)
+	}
+	for _, assignment := range assignments {
+		var kassignment kmsg.ConsumerMemberAssignment
+		if err := kassignment.ReadFrom(assignment.MemberAssignment); err != nil {
+			g.cl.cfg.logger.Log(LogLevelDebug, "unable to decode member assignment for the balance plan", "group", g.cfg.group, "member_id", assignment.MemberID, "err", err)
+			continue
+		}
+		topics := make(map[string][]int32, len(kassignment.Topics))
+		for _, topic := range kassignment.Topics {
+			topics[topic.Topic] = append(topics[topic.Topic], topic.Partitions...)
+		}
+		plan[assignment.MemberID] = topics
+	}
+
+	if g.cl.cfg.logger.Level() >= LogLevelDebug {
+		memberIDs := make([]string, 0, len(plan))
+		for member := range plan {
+			memberIDs = append(memberIDs, member)
+		}
+		sort.Strings(memberIDs)
+		for _, member := range memberIDs {
+			g.cl.cfg.logger.Log(LogLevelDebug, "balance plan for member", "group", g.cfg.group, "generation", g.generation, "member_id", member, "assigned", plan[member])
+		}
+	}
+
+	g.mu.Lock()
+	g.lastBalancePlan = plan
+	g.mu.Unlock()
 }
 
 // helper func; range and roundrobin use v0
@@ -389,6 +476,158 @@ func memberMetadataV0(interests []string) []byte {
 	return meta.AppendTo(nil)
 }
 
+// balancePartition is a topic and partition, for balancers that track state
+// per partition while planning.
+type balancePartition struct {
+	topic     string
+	partition int32
+}
+
+// interestedMembers returns the members interested in each topic, in member
+// order, as well as all topics any member is interested in, sorted.
+func interestedMembers(b *ConsumerBalancer) (map[string][]*kmsg.JoinGroupResponseMember, []string) {
+	interested := make(map[string][]*kmsg.JoinGroupResponseMember)
+	b.EachMember(func(member *kmsg.JoinGroupResponseMember, meta *kmsg.ConsumerMemberMetadata) {
+		for _, topic := range meta.Topics {
+			interested[topic] = append(interested[topic], member)
+		}
+	})
+	sorted := make([]string, 0, len(interested))
+	for topic := range interested {
+		sorted = append(sorted, topic)
+	}
+	sort.Strings(sorted)
+	return interested, sorted
+}
+
+// leastLoaded returns the first member in member order with the least load
+// that passes ok, or nil if no member passes. A nil ok passes every member.
+func leastLoaded(members []*kmsg.JoinGroupResponseMember, load map[string]int, ok func(*kmsg.JoinGroupResponseMember) bool) *kmsg.JoinGroupResponseMember {
+	var least *kmsg.JoinGroupResponseMember
+	for _, member := range members {
+		if (ok == nil || ok(member)) && (least == nil || load[member.MemberID] < load[least.MemberID]) {
+			least = member
+		}
+	}
+	return least
+}
+
+// LoggingBalancer returns a group balancer that wraps the given balancer and,
+// at the debug level, logs the member metadata it receives, the plan it
+// returns, and the assignments it parses.
+//
+// The returned balancer uses the wrapped balancer's protocol name and
+// cooperative-ness, meaning the group negotiates the protocol as if the inner
+// balancer were used directly. This is useful for debugging the decisions of
+// custom balancers.
+func LoggingBalancer(logger Logger, inner GroupBalancer) GroupBalancer {
+	return &loggingBalancer{logger, inner}
+}
+
+type loggingBalancer struct {
+	logger Logger
+	inner  GroupBalancer
+}
+
+type loggingMemberBalancer struct {
+	l     *loggingBalancer
+	inner GroupMemberBalancer
+}
+
+func (l *loggingBalancer) debug() bool { return l.logger.Level() >= LogLevelDebug }
+
+func (l *loggingBalancer) ProtocolName() string { return l.inner.ProtocolName() }
+func (l *loggingBalancer) IsCooperative() bool  { return l.inner.IsCooperative() }
+
+func (l *loggingBalancer) JoinGroupMetadata(interests []string, currentAssignment map[string][]int32, generation int32) []byte {
+	return l.inner.JoinGroupMetadata(interests, currentAssignment, generation)
+}
+
+func (l *loggingBalancer) ParseSyncAssignment(assignment []byte) (map[string][]int32, error) {
+	parsed, err := l.inner.ParseSyncAssignment(assignment)
+	if l.debug() {
+		l.logger.Log(LogLevelDebug, "balancer parsed sync assignment", "protocol", l.inner.ProtocolName(), "assigned", tpsFmt(parsed), "err", err)
+	}
+	return parsed, err
+}
+
+func (l *loggingBalancer) ParseStandbySyncAssignment(assignment []byte) (map[string][]int32, error) {
+	sb, ok := l.inner.(GroupStandbyBalancer)
+	if !ok {
+		return nil, nil
+	}
+	parsed, err := sb.ParseStandbySyncAssignment(assignment)
+	if l.debug() {
+		l.logger.Log(LogLevelDebug, "balancer parsed standby sync assignment", "protocol", l.inner.ProtocolName(), "standby", tpsFmt(parsed), "err", err)
+	}
+	return parsed, err
+}
+
+func (l *loggingBalancer) MemberBalancer(members []kmsg.JoinGroupResponseMember) (GroupMemberBalancer, map[string]struct{}, error) {
+	if l.debug() {
+		for i := range members {
+			member := &members[i]
+			var instanceID string
+			if member.InstanceID != nil {
+				instanceID = *member.InstanceID
+			}
+			meta := kmsg.NewConsumerMemberMetadata()
+			if err := meta.ReadFrom(member.ProtocolMetadata); err != nil {
+				l.logger.Log(LogLevelDebug, "balancer received group member",
+					"protocol", l.inner.ProtocolName(),
+					"id", member.MemberID,
+					"instance_id", instanceID,
+					"metadata", member.ProtocolMetadata,
+				)
+				continue
+			}
+			owned := make(map[string][]int32, len(meta.OwnedPartitions))
+			for _, t := range meta.OwnedPartitions {
+				owned[t.Topic] = t.Partitions
+			}
+			l.logger.Log(LogLevelDebug, "balancer received group member",
+				"protocol", l.inner.ProtocolName(),
+				"id", member.MemberID,
+				"instance_id", instanceID,
+				"topics", meta.Topics,
+				"owned", tpsFmt(owned),
+				"user_data", meta.UserData,
+			)
+		}
+	}
+	b, topics, err := l.inner.MemberBalancer(members)
+	if err != nil {
+		l.logger.Log(LogLevelDebug, "balancer unable to create member balancer", "protocol", l.inner.ProtocolName(), "err", err)
+		return nil, nil, err
+	}
+	return &loggingMemberBalancer{l, b}, topics, nil
+}
+
+// unwrapConsumerBalancer returns the *ConsumerBalancer backing b, looking
+// through the member balancer that LoggingBalancer wraps around it.
+func unwrapConsumerBalancer(b GroupMemberBalancer) (*ConsumerBalancer, bool) {
+	if l, ok := b.(*loggingMemberBalancer); ok {
+		b = l.inner
+	}
+	cb, ok := b.(*ConsumerBalancer)
+	return cb, ok
+}
+
+func (m *loggingMemberBalancer) Balance(topics map[string]int32) IntoSyncAssignment {
+	into := m.inner.Balance(topics)
+	if !m.l.debug() {
+		return into
+	}
+	if p, ok := into.(*BalancePlan); ok {
+		m.l.logger.Log(LogLevelDebug, "balancer produced plan", "protocol", m.l.inner.ProtocolName(), "plan", p.String())
+		return into
+	}
+	for _, assignment := range into.IntoSyncAssignment() {
+		m.l.logger.Log(LogLevelDebug, "balancer produced member assignment", "protocol", m.l.inner.ProtocolName(), "id", assignment.MemberID, "assignment", assignment.MemberAssignment)
+	}
+	return into
+}
+
 ///////////////////
 // Balance Plans //
 ///////////////////
@@ -722,6 +961,527 @@ func CooperativeStickyBalancer() GroupBalancer {
 	return &stickyBalancer{cooperative: true}
 }
 
+// SpreadBalancer returns a group balancer that spreads each topic's partitions
+// across as many distinct members as possible: no member receives a second
+// partition of a topic until every member interested in that topic has one.
+// Among members holding equally many partitions of a topic, the member with
+// the fewest partitions overall is chosen, keeping the whole group balanced.
+//
+// Suppose there are three members M0, M1, and M2, topic t0 has two partitions,
+// and topic t1 has four partitions. The partition balancing will be
+//
+//     M0: [t0p0, t1p1]
+//     M1: [t0p1, t1p2]
+//     M2: [t1p0, t1p3]
+//
+// This is useful for workloads where a topic's partitions should be processed
+// in parallel across the fleet, rather than concentrated on a few members.
+// This balancer is eager and does not attempt to be sticky.
+func SpreadBalancer() GroupBalancer {
+	return new(spreadBalancer)
+}
+
+type spreadBalancer struct{}
+
+func (*spreadBalancer) ProtocolName() string { return "spread" }
+func (*spreadBalancer) IsCooperative() bool  { return false }
+func (*spreadBalancer) JoinGroupMetadata(interests []string, _ map[string][]int32, _ int32) []byte {
+	return memberMetadataV0(interests)
+}
+
+func (*spreadBalancer) ParseSyncAssignment(assignment []byte) (map[string][]int32, error) {
+	return ParseConsumerSyncAssignment(assignment)
+}
+
+func (s *spreadBalancer) MemberBalancer(members []kmsg.JoinGroupResponseMember) (GroupMemberBalancer, map[string]struct{}, error) {
+	b, err := NewConsumerBalancer(s, members)
+	return b, b.MemberTopics(), err
+}
+
+func (*spreadBalancer) Balance(b *ConsumerBalancer, topics map[string]int32) IntoSyncAssignment {
+	interested, sorted := interestedMembers(b)
+
+	plan := b.NewPlan()
+	load := make(map[string]int, len(b.Members()))
+	for _, topic := range sorted {
+		members := interested[topic]
+		topicLoad := make(map[string]int, len(members))
+		for partition := int32(0); partition < topics[topic]; partition++ {
+			var least *kmsg.JoinGroupResponseMember
+			for _, member := range members {
+				if least == nil {
+					least = member
+					continue
+				}
+				id, leastID := member.MemberID, least.MemberID
+				if topicLoad[id] < topicLoad[leastID] ||
+					topicLoad[id] == topicLoad[leastID] && load[id] < load[leastID] {
+					least = member
+				}
+			}
+			plan.AddPartition(least, topic, partition)
+			topicLoad[least.MemberID]++
+			load[least.MemberID]++
+		}
+	}
+	return plan
+}
+
+// ActiveStandbyBalancer returns a group balancer that assigns every partition
+// to one active member and up to standbys additional, distinct standby
+// members. Only active partitions are consumed; standby partitions are
+// surfaced to HookGroupStandbyAssigned, allowing members to keep state for
+// them warm. A negative standbys is treated as zero.
+//
+// Members encode the active and standby partitions they were last assigned in
+// their join metadata. When balancing, the leader keeps active
+// partitions on their prior owner as long as that stays balanced, and
+// otherwise prefers promoting a prior standby before falling back to the
+// least loaded interested member. Standby partitions likewise stay with their
+// prior owner where possible. If fewer members are interested in a topic
+// than requested, each partition of that topic has fewer standbys.
+//
+// This balancer is eager, and because it remembers its own last standby
+// assignment, each client should use its own ActiveStandbyBalancer.
+func ActiveStandbyBalancer(standbys int) GroupBalancer {
+	if standbys < 0 {
+		standbys = 0
+	}
+	return &activeStandbyBalancer{standbys: standbys}
+}
+
+type activeStandbyBalancer struct {
+	standbys int
+
+	// Eager members revoke everything before joining, so we remember
+	// what we were last assigned to encode it in our join metadata.
+	mu      sync.Mutex
+	active  map[string][]int32
+	standby map[string][]int32
+}
+
+func (*activeStandbyBalancer) ProtocolName() string { return "active-standby" }
+func (*activeStandbyBalancer) IsCooperative() bool  { return false }
+func (s *activeStandbyBalancer) JoinGroupMetadata(interests []string, _ map[string][]int32, _ int32) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	meta := kmsg.NewConsumerMemberMetadata()
+	meta.Version = 1
+	meta.Topics = interests
+	for topic, partitions := range s.active {
+		metaPart := kmsg.NewConsumerMemberMetadataOwnedPartition()
+		metaPart.Topic = topic
+		metaPart.Partitions = partitions
+		meta.OwnedPartitions = append(meta.OwnedPartitions, metaPart)
+	}
+	metaOwned := meta.OwnedPartitions
+	sort.Slice(metaOwned, func(i, j int) bool { return metaOwned[i].Topic < metaOwned[j].Topic })
+
+	for _, owned := range metaOwned {
+		sort.Slice(owned.Partitions, func(i, j int) bool { return owned.Partitions[i] < owned.Partitions[j] })
+	}
+
+	standby := consumerMemberAssignment(s.standby)
+	meta.UserData = standby.AppendTo(nil)
+	return meta.AppendTo(nil)
+}
+
+func (s *activeStandbyBalancer) ParseSyncAssignment(assignment []byte) (map[string][]int32, error) {
+	active, err := ParseConsumerSyncAssignment(assignment)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.active = make(map[string][]int32, len(active))
+	for topic, partitions := range active {
+		s.active[topic] = append([]int32(nil), partitions...)
+	}
+	s.mu.Unlock()
+	return active, nil
+}
+
+func (s *activeStandbyBalancer) ParseStandbySyncAssignment(assignment []byte) (map[string][]int32, error) {
+	var kassignment kmsg.ConsumerMemberAssignment
+	if err := kassignment.ReadFrom(assignment); err != nil {
+		return nil, fmt.Errorf("sync assignment parse failed: %v", err)
+	}
+	standby, err := ParseConsumerSyncAssignment(kassignment.UserData)
+	if err != nil {
+		return nil, fmt.Errorf("standby assignment parse failed: %v", err)
+	}
+	s.mu.Lock()
+	s.standby = make(map[string][]int32, len(standby))
+	for topic, partitions := range standby {
+		s.standby[topic] = append([]int32(nil), partitions...)
+	}
+	s.mu.Unlock()
+	return standby, nil
+}
+
+func (s *activeStandbyBalancer) MemberBalancer(members []kmsg.JoinGroupResponseMember) (GroupMemberBalancer, map[string]struct{}, error) {
+	b, err := NewConsumerBalancer(s, members)
+	return b, b.MemberTopics(), err
+}
+
+func (s *activeStandbyBalancer) Balance(b *ConsumerBalancer, topics map[string]int32) IntoSyncAssignment {
+	var (
+		interested, sorted = interestedMembers(b)
+		priorActive        = make(map[balancePartition]string)
+		priorStandby       = make(map[balancePartition]map[string]bool)
+	)
+	b.EachMember(func(member *kmsg.JoinGroupResponseMember, meta *kmsg.ConsumerMemberMetadata) {
+		for _, owned := range meta.OwnedPartitions {
+			for _, partition := range owned.Partitions {
+				tp := balancePartition{owned.Topic, partition}
+				if _, exists := priorActive[tp]; !exists {
+					priorActive[tp] = member.MemberID
+				}
+			}
+		}
+		var standby kmsg.ConsumerMemberAssignment
+		if len(meta.UserData) == 0 || standby.ReadFrom(meta.UserData) != nil {
+			return
+		}
+		for _, topic := range standby.Topics {
+			for _, partition := range topic.Partitions {
+				tp := balancePartition{topic.Topic, partition}
+				if priorStandby[tp] == nil {
+					priorStandby[tp] = make(map[string]bool)
+				}
+				priorStandby[tp][member.MemberID] = true
+			}
+		}
+	})
+
+	var allParts []balancePartition
+	for _, topic := range sorted {
+		for partition := int32(0); partition < topics[topic]; partition++ {
+			allParts = append(allParts, balancePartition{topic, partition})
+		}
+	}
+
+	var (
+		active      = b.NewPlan()
+		standby     = b.NewPlan()
+		activeLoad  = make(map[string]int)
+		standbyLoad = make(map[string]int)
+		activeOf    = make(map[balancePartition]string, len(allParts))
+		maxActive   = (len(allParts) + len(b.Members()) - 1) / len(b.Members())
+	)
+
+	setActive := func(member *kmsg.JoinGroupResponseMember, tp balancePartition) {
+		active.AddPartition(member, tp.topic, tp.partition)
+		activeLoad[member.MemberID]++
+		activeOf[tp] = member.MemberID
+	}
+
+	// First, keep active partitions on their prior owner as long as that
+	// owner is not over its fair share.
+	for _, tp := range allParts {
+		prior, exists := priorActive[tp]
+		if !exists {
+			continue
+		}
+		for _, member := range interested[tp.topic] {
+			if member.MemberID == prior && activeLoad[prior] < maxActive {
+				setActive(member, tp)
+				break
+			}
+		}
+	}
+
+	// Second, promote a prior standby for what remains, and otherwise
+	// fall back to the least loaded interested member.
+	for _, tp := range allParts {
+		if _, assigned := activeOf[tp]; assigned {
+			continue
+		}
+		members := interested[tp.topic]
+		member := leastLoaded(members, activeLoad, func(m *kmsg.JoinGroupResponseMember) bool {
+			return priorStandby[tp][m.MemberID] && activeLoad[m.MemberID] < maxActive
+		})
+		if member == nil {
+			member = leastLoaded(members, activeLoad, nil)
+		}
+		setActive(member, tp)
+	}
+
+	// Finally, assign standbys to distinct members other than the active
+	// member, keeping prior standbys first.
+	for _, tp := range allParts {
+		members := interested[tp.topic]
+		chosen := map[string]bool{activeOf[tp]: true}
+		for len(chosen)-1 < s.standbys {
+			member := leastLoaded(members, standbyLoad, func(m *kmsg.JoinGroupResponseMember) bool {
+				return !chosen[m.MemberID] && priorStandby[tp][m.MemberID]
+			})
+			if member == nil {
+				member = leastLoaded(members, standbyLoad, func(m *kmsg.JoinGroupResponseMember) bool { return !chosen[m.MemberID] })
+			}
+			if member == nil {
+				break
+			}
+			standby.AddPartition(member, tp.topic, tp.partition)
+			standbyLoad[member.MemberID]++
+			chosen[member.MemberID] = true
+		}
+	}
+
+	return &activeStandbyPlan{active, standby}
+}
+
+// activeStandbyPlan encodes the standby plan for each member in the UserData
+// of the member's active kmsg.ConsumerMemberAssignment.
+type activeStandbyPlan struct {
+	active  *BalancePlan
+	standby *BalancePlan
+}
+
+func (p *activeStandbyPlan) String() string {
+	return fmt.Sprintf("active{%s}, standby{%s}", p.active, p.standby)
+}
+
+func (p *activeStandbyPlan) IntoSyncAssignment() []kmsg.SyncGroupRequestGroupAssignment {
+	kassignments := make([]kmsg.SyncGroupRequestGroupAssignment, 0, len(p.active.plan))
+	for member, assignment := range p.active.plan {
+		kassignment := consumerMemberAssignment(assignment)
+		standby := consumerMemberAssignment(p.standby.plan[member])
+		kassignment.UserData = standby.AppendTo(nil)
+		syncAssn := kmsg.NewSyncGroupRequestGroupAssignment()
+		syncAssn.MemberID = member
+		syncAssn.MemberAssignment = kassignment.AppendTo(nil)
+		kassignments = append(kassignments, syncAssn)
+	}
+	sort.Slice(kassignments, func(i, j int) bool { return kassignments[i].MemberID < kassignments[j].MemberID })
+	return kassignments
+}
+
+// PartitionRangeBalancer returns a group balancer where each member declares
+// the range of partitions [start, end) that it would like to own for every
+// topic it consumes. This is meant for sharded architectures where each
+// process should deterministically own a shard of a large topic, while still
+// using the group for liveness and fencing.
+//
+// The range is encoded in the member's join metadata. When balancing, the
+// leader assigns every partition within a member's range to that member; if
+// the ranges of multiple members overlap, the partition goes to the least
+// loaded of them. Partitions that no live member claims, such as the range
+// of a member that failed, are assigned to the least loaded interested
+// member, and move back once a member claiming them rejoins. A range where
+// start is negative or end is not greater than start claims nothing.
+//
+// This balancer is eager. Every member of the group must use this balancer,
+// each with its own range.
+func PartitionRangeBalancer(start, end int32) GroupBalancer {
+	return &partitionRangeBalancer{start, end}
+}
+
+type partitionRangeBalancer struct {
+	start, end int32
+}
+
+func (*partitionRangeBalancer) ProtocolName() string { return "partition-range" }
+func (*partitionRangeBalancer) IsCooperative() bool  { return false }
+func (r *partitionRangeBalancer) JoinGroupMetadata(interests []string, _ map[string][]int32, _ int32) []byte {
+	meta := kmsg.NewConsumerMemberMetadata()
+	meta.Topics = interests
+	meta.UserData = kbin.AppendInt16(meta.UserData, 0) // version
+	meta.UserData = kbin.AppendInt32(meta.UserData, r.start)
+	meta.UserData = kbin.AppendInt32(meta.UserData, r.end)
+	return meta.AppendTo(nil)
+}
+
+func (*partitionRangeBalancer) ParseSyncAssignment(assignment []byte) (map[string][]int32, error) {
+	return ParseConsumerSyncAssignment(assignment)
+}
+
+func (r *partitionRangeBalancer) MemberBalancer(members []kmsg.JoinGroupResponseMember) (GroupMemberBalancer, map[string]struct{}, error) {
+	b, err := NewConsumerBalancer(r, members)
+	return b, b.MemberTopics(), err
+}
+
+// parsePartitionRange returns the [start, end) range a member encoded in its
+// join metadata, and false if the member did not claim a valid range.
+func parsePartitionRange(userData []byte) (start, end int32, ok bool) {
+	b := kbin.Reader{Src: userData}
+	version := b.Int16()
+	start, end = b.Int32(), b.Int32()
+	if b.Complete() != nil || version != 0 || start < 0 || end <= start {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+func (*partitionRangeBalancer) Balance(b *ConsumerBalancer, topics map[string]int32) IntoSyncAssignment {
+	type partitionRange struct{ start, end int32 }
+
+	interested, sorted := interestedMembers(b)
+	ranges := make(map[string]partitionRange) // member => claimed range, if valid
+	b.EachMember(func(member *kmsg.JoinGroupResponseMember, meta *kmsg.ConsumerMemberMetadata) {
+		if start, end, ok := parsePartitionRange(meta.UserData); ok {
+			ranges[member.MemberID] = partitionRange{start, end}
+		}
+	})
+
+	plan := b.NewPlan()
+	load := make(map[string]int, len(b.Members()))
+	for _, topic := range sorted {
+		members := interested[topic]
+		var unclaimed []int32
+		for partition := int32(0); partition < topics[topic]; partition++ {
+			member := leastLoaded(members, load, func(m *kmsg.JoinGroupResponseMember) bool {
+				r, ok := ranges[m.MemberID]
+				return ok && r.start <= partition && partition < r.end
+			})
+			if member == nil {
+				unclaimed = append(unclaimed, partition)
+				continue
+			}
+			plan.AddPartition(member, topic, partition)
+			load[member.MemberID]++
+		}
+
+		// Only once every claimed partition is placed do we spread
+		// what remains, so that fallback partitions go to the members
+		// with the smallest claimed ranges.
+		for _, partition := range unclaimed {
+			member := leastLoaded(members, load, nil)
+			plan.AddPartition(member, topic, partition)
+			load[member.MemberID]++
+		}
+	}
+	return plan
+}
+
+// StaticBalancer returns a group balancer that applies a static assignment,
+// using the group only for failure detection. Each member identifies itself
+// with a stable key, encoded in its join metadata, and the leader assigns each
+// member the partitions listed for its key in assignments (key => topic =>
+// partitions).
+//
+// Partitions that are not listed for any live member, such as those of a
+// member that failed, are assigned to the least loaded member interested in
+// the partition's topic: this is round robin across equally loaded members,
+// such that members whose key is not in the assignments pick up the slack.
+// Listed partitions that a member is not interested in or that do not exist
+// are ignored, and a partition listed for multiple keys
+// goes to the first live member in member order.
+//
+// This balancer is eager. Every member of the group must use this balancer,
+// each with its own key; the assignments used are those of whichever member
+// is the leader, so every member should use the same assignments.
+func StaticBalancer(key string, assignments map[string]map[string][]int32) GroupBalancer {
+	return &staticBalancer{key, assignments}
+}
+
+type staticBalancer struct {
+	key         string
+	assignments map[string]map[string][]int32
+}
+
+func (*staticBalancer) ProtocolName() string { return "static" }
+func (*staticBalancer) IsCooperative() bool  { return false }
+func (s *staticBalancer) JoinGroupMetadata(interests []string, _ map[string][]int32, _ int32) []byte {
+	meta := kmsg.NewConsumerMemberMetadata()
+	meta.Topics = interests
+	meta.UserData = kbin.AppendInt16(meta.UserData, 0) // version
+	meta.UserData = kbin.AppendString(meta.UserData, s.key)
+	return meta.AppendTo(nil)
+}
+
+func (*staticBalancer) ParseSyncAssignment(assignment []byte) (map[string][]int32, error) {
+	return ParseConsumerSyncAssignment(assignment)
+}
+
+func (s *staticBalancer) MemberBalancer(members []kmsg.JoinGroupResponseMember) (GroupMemberBalancer, map[string]struct{}, error) {
+	b, err := NewConsumerBalancer(s, members)
+	return b, b.MemberTopics(), err
+}
+
+// parseStaticKey returns the key a member encoded in its join metadata, and
+// false if the member did not encode a key.
+func parseStaticKey(userData []byte) (string, bool) {
+	b := kbin.Reader{Src: userData}
+	version := b.Int16()
+	key := b.String()
+	if b.Complete() != nil || version != 0 {
+		return "", false
+	}
+	return key, true
+}
+
+func (s *staticBalancer) Balance(b *ConsumerBalancer, topics map[string]int32) IntoSyncAssignment {
+	var (
+		plan               = b.NewPlan()
+		interested, sorted = interestedMembers(b)
+		assigned           = make(map[balancePartition]bool)
+		load               = make(map[string]int)
+	)
+	b.EachMember(func(member *kmsg.JoinGroupResponseMember, meta *kmsg.ConsumerMemberMetadata) {
+		key, ok := parseStaticKey(meta.UserData)
+		if !ok {
+			return
+		}
+		wants := make(map[string]bool, len(meta.Topics))
+		for _, topic := range meta.Topics {
+			wants[topic] = true
+		}
+		for topic, partitions := range s.assignments[key] {
+			if !wants[topic] {
+				continue
+			}
+			for _, partition := range partitions {
+				tp := balancePartition{topic, partition}
+				if partition < 0 || partition >= topics[topic] || assigned[tp] {
+					continue
+				}
+				plan.AddPartition(member, topic, partition)
+				assigned[tp] = true
+				load[member.MemberID]++
+			}
+		}
+	})
+
+	for _, topic := range sorted {
+		for partition := int32(0); partition < topics[topic]; partition++ {
+			if assigned[balancePartition{topic, partition}] {
+				continue
+			}
+			least := leastLoaded(interested[topic], load, nil)
+			plan.AddPartition(least, topic, partition)
+			load[least.MemberID]++
+		}
+	}
+	return plan
+}
+
+// movedFrom returns how many partitions members previously owned that are not
+// planned for the same member, as well as how many partitions members
+// previously owned in total.
+//
+// For cooperative plans, this should be called after AdjustCooperative:
+// partitions that are migrating are counted once here, and are then not owned
+// by anybody in the follow up rebalance.
+func (p *BalancePlan) movedFrom(b *ConsumerBalancer) (moved, owned int) {
+	b.EachMember(func(member *kmsg.JoinGroupResponseMember, meta *kmsg.ConsumerMemberMetadata) {
+		planned := p.plan[member.MemberID]
+		for _, otopic := range meta.OwnedPartitions {
+			pmap := make(map[int32]struct{}, len(planned[otopic.Topic]))
+			for _, partition := range planned[otopic.Topic] {
+				pmap[partition] = struct{}{}
+			}
+			for _, opartition := range otopic.Partitions {
+				owned++
+				if _, exists := pmap[opartition]; !exists {
+					moved++
+				}
+			}
+		}
+	})
+	return moved, owned
+}
+
 // AdjustCooperative performs the final adjustment to a plan for cooperative
 // balancing.
 //
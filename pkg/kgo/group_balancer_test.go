@@ -1,6 +1,8 @@
 package kgo
 
 import (
+	"io"
+	"sort"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -99,3 +101,323 @@ func Test_stickyAdjustCooperative(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func Test_balancePlanMovedFrom(t *testing.T) {
+	b := &ConsumerBalancer{
+		members: []kmsg.JoinGroupResponseMember{
+			{MemberID: "a"},
+			{MemberID: "b"},
+			{MemberID: "c"},
+		},
+		metadatas: []kmsg.ConsumerMemberMetadata{
+			{OwnedPartitions: []kmsg.ConsumerMemberMetadataOwnedPartition{
+				{Topic: "t1", Partitions: []int32{0, 1, 2}},
+				{Topic: "tdelete", Partitions: []int32{0}},
+			}},
+			{OwnedPartitions: []kmsg.ConsumerMemberMetadataOwnedPartition{
+				{Topic: "t1", Partitions: []int32{3}},
+			}},
+			{}, // new member, nothing owned
+		},
+	}
+
+	p := &BalancePlan{map[string]map[string][]int32{
+		"a": {"t1": {0, 1}},
+		"b": {"t1": {3}},
+		"c": {"t1": {2}},
+	}}
+
+	moved, owned := p.movedFrom(b)
+	if moved != 2 || owned != 5 {
+		t.Errorf("got moved %d owned %d, exp moved 2 owned 5", moved, owned)
+	}
+}
+
+func Test_loggingBalancerMatchesInner(t *testing.T) {
+	topics := map[string]int32{"t0": 3, "t1": 2}
+	for _, inner := range []GroupBalancer{
+		RoundRobinBalancer(),
+		RangeBalancer(),
+		StickyBalancer(),
+		CooperativeStickyBalancer(),
+	} {
+		t.Run(inner.ProtocolName(), func(t *testing.T) {
+			logging := LoggingBalancer(BasicLogger(io.Discard, LogLevelDebug, nil), inner)
+			if got, exp := logging.ProtocolName(), inner.ProtocolName(); got != exp {
+				t.Errorf("got protocol %q, exp %q", got, exp)
+			}
+			if got, exp := logging.IsCooperative(), inner.IsCooperative(); got != exp {
+				t.Errorf("got cooperative %v, exp %v", got, exp)
+			}
+
+			// The members already own a balanced assignment so that
+			// the sticky balancers deterministically keep it.
+			owned := map[string]map[string][]int32{
+				"a": {"t0": {0}, "t1": {0}},
+				"b": {"t0": {1}, "t1": {1}},
+				"c": {"t0": {2}},
+			}
+			var members []kmsg.JoinGroupResponseMember
+			for _, id := range []string{"a", "b", "c"} {
+				meta := inner.JoinGroupMetadata([]string{"t0", "t1"}, owned[id], 1)
+				if diff := cmp.Diff(meta, logging.JoinGroupMetadata([]string{"t0", "t1"}, owned[id], 1)); diff != "" {
+					t.Errorf("join metadata: %s", diff)
+				}
+				members = append(members, kmsg.JoinGroupResponseMember{MemberID: id, ProtocolMetadata: meta})
+			}
+
+			balance := func(b GroupBalancer) map[string]map[string][]int32 {
+				mb, _, err := b.MemberBalancer(members)
+				if err != nil {
+					t.Fatalf("unable to create member balancer: %v", err)
+				}
+				plan := make(map[string]map[string][]int32)
+				for _, assn := range mb.Balance(topics).IntoSyncAssignment() {
+					if plan[assn.MemberID], err = b.ParseSyncAssignment(assn.MemberAssignment); err != nil {
+						t.Fatalf("unable to parse assignment: %v", err)
+					}
+				}
+				return plan
+			}
+			if diff := cmp.Diff(balance(inner), balance(logging)); diff != "" {
+				t.Errorf("plan: %s", diff)
+			}
+		})
+	}
+}
+
+func Test_setLastBalancePlan(t *testing.T) {
+	g := newStubGroup(t, nil)
+	if plan := g.cl.LastBalancePlan(); plan != nil {
+		t.Errorf("got plan %v before balancing, exp nil", plan)
+	}
+
+	assignment := func(topics map[string][]int32) []byte {
+		kassignment := kmsg.NewConsumerMemberAssignment()
+		for topic, partitions := range topics {
+			kassignment.Topics = append(kassignment.Topics, kmsg.ConsumerMemberAssignmentTopic{Topic: topic, Partitions: partitions})
+		}
+		return kassignment.AppendTo(nil)
+	}
+	members := []kmsg.JoinGroupResponseMember{{MemberID: "a"}, {MemberID: "b"}, {MemberID: "c"}}
+	g.setLastBalancePlan(members, []kmsg.SyncGroupRequestGroupAssignment{
+		{MemberID: "a", MemberAssignment: assignment(map[string][]int32{"t0": {0, 1}, "t1": {0}})},
+		{MemberID: "b", MemberAssignment: assignment(nil)},
+		// c is missing entirely, as with balancers that skip members
+		// that are assigned nothing.
+	})
+
+	exp := map[string]map[string][]int32{
+		"a": {"t0": {0, 1}, "t1": {0}},
+		"b": {},
+		"c": {},
+	}
+	if diff := cmp.Diff(exp, g.cl.LastBalancePlan()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type movedHook struct{ moved, owned []int }
+
+func (h *movedHook) OnGroupPartitionsMoved(_ string, _ int32, moved, owned int) {
+	h.moved = append(h.moved, moved)
+	h.owned = append(h.owned, owned)
+}
+
+func Test_balanceGroupMovedHook(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		balancer GroupBalancer
+	}{
+		{"plain", CooperativeStickyBalancer()},
+		{"logging", LoggingBalancer(BasicLogger(io.Discard, LogLevelDebug, nil), CooperativeStickyBalancer())},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h := new(movedHook)
+			g := newStubGroup(t, nil, Balancers(test.balancer), WithHooks(h))
+
+			// The group knows t has two partitions; a owns both, and
+			// the new member b will take one of them.
+			tp := g.tps.load()["t"]
+			prior := tp.load()
+			tp.v.Store(&topicPartitionsData{partitions: make([]*topicPartition, 2)})
+			defer tp.v.Store(prior)
+
+			members := []kmsg.JoinGroupResponseMember{
+				{MemberID: "a", ProtocolMetadata: test.balancer.JoinGroupMetadata([]string{"t"}, map[string][]int32{"t": {0, 1}}, 1)},
+				{MemberID: "b", ProtocolMetadata: test.balancer.JoinGroupMetadata([]string{"t"}, nil, 1)},
+			}
+			if _, err := g.balanceGroup(test.balancer.ProtocolName(), members); err != nil {
+				t.Fatalf("unable to balance: %v", err)
+			}
+			if len(h.moved) != 1 || h.moved[0] != 1 || h.owned[0] != 2 {
+				t.Errorf("got moved %v owned %v, exp one call with moved 1 owned 2", h.moved, h.owned)
+			}
+		})
+	}
+}
+
+func Test_activeStandbyBalancerPromotesStandby(t *testing.T) {
+	balancers := map[string]*activeStandbyBalancer{
+		"a": ActiveStandbyBalancer(1).(*activeStandbyBalancer),
+		"b": ActiveStandbyBalancer(1).(*activeStandbyBalancer),
+		"c": ActiveStandbyBalancer(1).(*activeStandbyBalancer),
+	}
+
+	balance := func(ids ...string) (active, standby map[string]map[string][]int32) {
+		var members []kmsg.JoinGroupResponseMember
+		for _, id := range ids {
+			members = append(members, kmsg.JoinGroupResponseMember{
+				MemberID:         id,
+				ProtocolMetadata: balancers[id].JoinGroupMetadata([]string{"t"}, nil, 0),
+			})
+		}
+		b, _, err := balancers[ids[0]].MemberBalancer(members)
+		if err != nil {
+			t.Fatalf("unable to create member balancer: %v", err)
+		}
+		active = make(map[string]map[string][]int32)
+		standby = make(map[string]map[string][]int32)
+		for _, assn := range b.Balance(map[string]int32{"t": 3}).IntoSyncAssignment() {
+			bal := balancers[assn.MemberID]
+			if active[assn.MemberID], err = bal.ParseSyncAssignment(assn.MemberAssignment); err != nil {
+				t.Fatalf("unable to parse active assignment: %v", err)
+			}
+			if standby[assn.MemberID], err = bal.ParseStandbySyncAssignment(assn.MemberAssignment); err != nil {
+				t.Fatalf("unable to parse standby assignment: %v", err)
+			}
+		}
+		return active, standby
+	}
+
+	active, standby := balance("a", "b", "c")
+	expActive := map[string]map[string][]int32{
+		"a": {"t": {0}},
+		"b": {"t": {1}},
+		"c": {"t": {2}},
+	}
+	expStandby := map[string]map[string][]int32{
+		"a": {"t": {1, 2}},
+		"b": {"t": {0}},
+		"c": {},
+	}
+	if diff := cmp.Diff(expActive, active); diff != "" {
+		t.Errorf("initial active: %s", diff)
+	}
+	if diff := cmp.Diff(expStandby, standby); diff != "" {
+		t.Errorf("initial standby: %s", diff)
+	}
+
+	// When b leaves, a was the standby for t1 and should be promoted.
+	active, standby = balance("a", "c")
+	expActive = map[string]map[string][]int32{
+		"a": {"t": {0, 1}},
+		"c": {"t": {2}},
+	}
+	expStandby = map[string]map[string][]int32{
+		"a": {"t": {2}},
+		"c": {"t": {0, 1}},
+	}
+	if diff := cmp.Diff(expActive, active); diff != "" {
+		t.Errorf("promoted active: %s", diff)
+	}
+	if diff := cmp.Diff(expStandby, standby); diff != "" {
+		t.Errorf("promoted standby: %s", diff)
+	}
+}
+
+func Test_balancers(t *testing.T) {
+	type member struct {
+		id       string
+		balancer GroupBalancer // the balancer the member joins with
+	}
+	spread := SpreadBalancer()
+	static := map[string]map[string][]int32{
+		"a": {"t0": {0, 1}},
+		"b": {"t0": {2, 3}},
+		"c": {"t0": {4, 5}}, // c has failed
+	}
+
+	for _, test := range []struct {
+		name    string
+		members []member
+		topics  map[string]int32
+		exp     map[string]map[string][]int32
+	}{
+		{
+			name: "spread",
+			members: []member{
+				{"m0", spread},
+				{"m1", spread},
+				{"m2", spread},
+			},
+			topics: map[string]int32{"t0": 2, "t1": 4},
+			exp: map[string]map[string][]int32{
+				"m0": {"t0": {0}, "t1": {1}},
+				"m1": {"t0": {1}, "t1": {2}},
+				"m2": {"t1": {0, 3}},
+			},
+		},
+
+		{
+			name: "partition range",
+			members: []member{
+				{"m0", PartitionRangeBalancer(0, 3)},
+				{"m1", PartitionRangeBalancer(3, 6)},
+				{"m2", PartitionRangeBalancer(6, 9)}, // m3, which claimed 9 through 12, has failed
+			},
+			topics: map[string]int32{"t0": 12},
+			exp: map[string]map[string][]int32{
+				"m0": {"t0": {0, 1, 2, 9}},
+				"m1": {"t0": {3, 4, 5, 10}},
+				"m2": {"t0": {6, 7, 8, 11}},
+			},
+		},
+
+		{
+			name: "static",
+			members: []member{
+				{"m0", StaticBalancer("a", static)},
+				{"m1", StaticBalancer("b", static)},
+				{"m2", StaticBalancer("unknown", static)},
+			},
+			topics: map[string]int32{"t0": 6},
+			exp: map[string]map[string][]int32{
+				"m0": {"t0": {0, 1}},
+				"m1": {"t0": {2, 3}},
+				"m2": {"t0": {4, 5}},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			interests := make([]string, 0, len(test.topics))
+			for topic := range test.topics {
+				interests = append(interests, topic)
+			}
+			sort.Strings(interests)
+
+			var members []kmsg.JoinGroupResponseMember
+			for _, m := range test.members {
+				members = append(members, kmsg.JoinGroupResponseMember{
+					MemberID:         m.id,
+					ProtocolMetadata: m.balancer.JoinGroupMetadata(interests, nil, 0),
+				})
+			}
+			leader := test.members[0].balancer
+			b, _, err := leader.MemberBalancer(members)
+			if err != nil {
+				t.Fatalf("unable to create member balancer: %v", err)
+			}
+
+			got := make(map[string]map[string][]int32)
+			for _, assn := range b.Balance(test.topics).IntoSyncAssignment() {
+				if got[assn.MemberID], err = leader.ParseSyncAssignment(assn.MemberAssignment); err != nil {
+					t.Fatalf("unable to parse assignment: %v", err)
+				}
+			}
+			if diff := cmp.Diff(test.exp, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
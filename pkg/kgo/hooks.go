@@ -3,6 +3,8 @@ package kgo
 import (
 	"net"
 	"time"
+
+	"github.com/twmb/franz-go/pkg/kmsg"
 )
 
 // Hook is a hook to be called when something happens in kgo.
@@ -189,6 +191,186 @@ type HookGroupManageError interface {
 	OnGroupManageError(error)
 }
 
+// HookGroupCallbackDuration is called after every user OnPartitionsAssigned,
+// OnPartitionsRevoked, and OnPartitionsLost callback returns.
+type HookGroupCallbackDuration interface {
+	// OnGroupCallbackDuration is passed the callback that was called
+	// ("OnAssigned", "OnRevoked", or "OnLost") and how long it took.
+	//
+	// A callback that is slow relative to the session or rebalance
+	// timeout can cause this member to be kicked from the group.
+	OnGroupCallbackDuration(phase string, d time.Duration)
+}
+
+// HookGroupInstanceFenced is called if the client, operating as a static
+// group member, is fenced by another member using the same instance ID.
+//
+// Retrying is futile until the other member leaves the group, so when fenced,
+// the client stops managing the group and no longer consumes. The client
+// should be closed, or the other member stopped and this client recreated.
+type HookGroupInstanceFenced interface {
+	// OnInstanceFenced is passed the group and the instance ID that
+	// was fenced.
+	OnInstanceFenced(group, instanceID string)
+}
+
+// HookGroupSessionBegin is called at the beginning of every group session,
+// after the client, operating as a group member, has joined and synced the
+// group and before any partitions are assigned or revoked.
+type HookGroupSessionBegin interface {
+	// OnGroupSessionBegin is passed the partitions that were added to
+	// and lost from this member's assignment since the prior session, as
+	// well as the generation of the new session.
+	//
+	// Eager balancers revoke everything before rejoining, so for eager
+	// balancers, everything is added and nothing is lost. This is called in the
+	// group management goroutine: the session does not continue until the
+	// hook returns. The input maps must not be modified.
+	OnGroupSessionBegin(added, lost map[string][]int32, generation int32)
+}
+
+// HookGroupRevokeDiscarded is called every time the client, operating as a
+// group member, invalidates buffered fetches before revoking partitions.
+//
+// Buffered records were fetched but not yet polled; discarding them means they
+// are fetched and processed again (by this member or another) after the
+// rebalance. This hook can be used to quantify reprocessing caused by
+// rebalances. Cooperative consumers discard all buffered records when
+// revoking, not only records for partitions that are being revoked, but
+// records for partitions that are kept are simply fetched again.
+type HookGroupRevokeDiscarded interface {
+	// OnGroupRevokeDiscarded is passed the number of buffered records
+	// that were discarded per topic and partition. The map is nil if
+	// nothing was discarded, and must not be modified.
+	OnGroupRevokeDiscarded(discarded map[string]map[int32]int)
+}
+
+// HookGroupOffsetCommit is called after every offset commit request issued by
+// the client while consuming as a group member completes, before the commit's
+// callback is called.
+type HookGroupOffsetCommit interface {
+	// OnGroupOffsetCommit is passed where the commit originated (see
+	// CommitOrigin), the commit request, and either the response or the
+	// error if no response was received.
+	//
+	// A response may still have per-partition errors. The request and
+	// response must not be modified.
+	OnGroupOffsetCommit(origin CommitOrigin, req *kmsg.OffsetCommitRequest, resp *kmsg.OffsetCommitResponse, err error)
+}
+
+// HookGroupPartitionsMoved is called when the client, operating as a group
+// leader, balances the group with a balancer that uses a *ConsumerBalancer and
+// returns a *BalancePlan (all balancers in this package do so, including when
+// wrapped with LoggingBalancer).
+//
+// This can be used to validate how sticky a balancer is in practice: the
+// sticky balancers aim to move as few partitions as possible while keeping the
+// group balanced, so rolling restarts should be met with few moves.
+type HookGroupPartitionsMoved interface {
+	// OnGroupPartitionsMoved is passed the group, the generation that
+	// was balanced, the number of previously owned partitions that are
+	// now planned for a different member (or for nobody), and the total
+	// number of partitions members reported owning in their join
+	// metadata.
+	//
+	// Eager members do not report owned partitions, so for groups with
+	// eager balancers, both counts are always zero.
+	OnGroupPartitionsMoved(group string, generation int32, moved, owned int)
+}
+
+// HookGroupStandbyAssigned is called after syncing a group whose balancer
+// implements GroupStandbyBalancer, such as ActiveStandbyBalancer.
+//
+// Standby partitions are not consumed. This hook can be used to keep state for
+// standby partitions warm (for example, by reading a changelog topic), so that
+// a standby can take over processing quickly once promoted to active in a
+// later rebalance.
+type HookGroupStandbyAssigned interface {
+	// OnGroupStandbyAssigned is passed the group and the standby
+	// partitions assigned to this member in the latest sync. An empty
+	// map means this member has no standby partitions.
+	OnGroupStandbyAssigned(group string, standby map[string][]int32)
+}
+
+// HookGroupUnstableOffsetFetch is called each time fetching committed offsets
+// fails with UNSTABLE_OFFSET_COMMIT and the client waits to retry.
+//
+// Kafka returns this error while a transaction that commits offsets for the
+// group is pending. If a transaction is hung, the consumer will not begin
+// consuming the affected partitions until the transaction times out, and
+// this hook can be used to alert on that.
+type HookGroupUnstableOffsetFetch interface {
+	// OnGroupUnstableOffsetFetch is passed the group, the first topic
+	// and partition that had an unstable offset, the number of times
+	// fetching has been retried (starting at 1), and how long the client
+	// will wait before retrying.
+	OnGroupUnstableOffsetFetch(group, topic string, partition int32, attempt int, backoff time.Duration)
+}
+
+// HookGroupGenerationChange is called whenever a join group response changes
+// the group generation this client is a member of.
+//
+// This is a primitive for fencing external side effects by generation: once
+// this is called with a new generation, any work done under a prior generation
+// can be rejected by an external system that tracks the latest generation.
+type HookGroupGenerationChange interface {
+	// OnGroupGenerationChange is passed the group, this member's ID, and
+	// the new generation. This is called after joining but before
+	// syncing, meaning the new generation's assignment is not yet known.
+	// This is called in the group management goroutine and should not
+	// block.
+	OnGroupGenerationChange(group, memberID string, generation int32)
+}
+
+// HookGroupOffsetFetch is called after every OffsetFetch request the client
+// issues to fetch committed offsets when it is assigned partitions as a group
+// member.
+//
+// This can be used to diagnose slow joins caused by a slow group coordinator
+// responding to offset fetches, separately from join and sync latency. If
+// fetching is retried due to unstable offsets (see
+// HookGroupUnstableOffsetFetch), this is called once per request.
+type HookGroupOffsetFetch interface {
+	// OnGroupOffsetFetch is passed the group, how many partitions were
+	// requested, how long the request took, and the request error, if
+	// any. The error does not include per-partition errors. If the
+	// request is canceled, the error is the context error.
+	OnGroupOffsetFetch(group string, partitions int, took time.Duration, err error)
+}
+
+// HookGroupRebalanceStuck is called if LeaveOnStuckRebalance is used and the
+// client, joining and syncing a group, waited too long for the rebalance to
+// complete and left the group to rejoin with a new member ID.
+type HookGroupRebalanceStuck interface {
+	// OnGroupRebalanceStuck is passed the group, the member ID that left
+	// the group (which may be empty if the member had not yet been
+	// assigned one), and how long the client waited.
+	OnGroupRebalanceStuck(group, memberID string, waited time.Duration)
+}
+
+// HookGroupHeartbeatLoopStart is called when a group member, having joined and
+// synced, begins heartbeating for its new generation. The loop begins
+// immediately after syncing, concurrently with OnPartitionsAssigned and while
+// committed offsets are still being fetched.
+type HookGroupHeartbeatLoopStart interface {
+	// OnGroupHeartbeatLoopStart is passed the group, the member ID, and
+	// the generation that the member is now heartbeating in.
+	OnGroupHeartbeatLoopStart(group, memberID string, generation int32)
+}
+
+// HookGroupHeartbeatLoopEnd is called when a group member stops heartbeating
+// for a generation. If the loop ended due to a rebalance or leaving the group,
+// this is called once the revoke that the loop waits for is done. Between
+// HookGroupHeartbeatLoopStart and this hook, the member is actively
+// heartbeating in its generation.
+type HookGroupHeartbeatLoopEnd interface {
+	// OnGroupHeartbeatLoopEnd is passed the group, the member ID, the
+	// generation that the member was heartbeating in, and the error that
+	// ended the loop: generally kerr.RebalanceInProgress when rejoining,
+	// or context.Canceled when leaving the group.
+	OnGroupHeartbeatLoopEnd(group, memberID string, generation int32, err error)
+}
+
 ///////////////////////////////
 // PRODUCE & CONSUME BATCHES //
 ///////////////////////////////
@@ -72,6 +72,72 @@ func (cl *Client) waitmeta(ctx context.Context, wait time.Duration, why string)
 	cl.metawait.c.Broadcast()
 }
 
+// TriggerMetadataRefresh forces an immediate metadata update, waits for it to
+// complete, and then checks for any new partitions to consume from. This can
+// be used after adding partitions to a topic to have the group consumer (or
+// direct consumer) begin consuming the new partitions right away, rather than
+// after the next periodic metadata refresh.
+//
+// This bypasses MetadataMinAge. If a metadata update is already in flight,
+// this waits for that update rather than issuing another. This returns an
+// error only if the context or client is canceled before the update
+// completes. It is safe to call at any time, even if the client is not
+// consuming.
+func (cl *Client) TriggerMetadataRefresh(ctx context.Context) error {
+	cl.metawait.mu.Lock()
+	last := cl.metawait.lastUpdate
+	cl.metawait.mu.Unlock()
+
+	cl.triggerUpdateMetadataNow("user triggered metadata refresh")
+
+	quit := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cl.metawait.mu.Lock()
+		defer cl.metawait.mu.Unlock()
+
+		for !quit {
+			if cl.metawait.lastUpdate.After(last) {
+				return
+			}
+			cl.metawait.c.Wait()
+		}
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-cl.ctx.Done():
+		err = ErrClientClosed
+	}
+	if err != nil {
+		cl.metawait.mu.Lock()
+		quit = true
+		cl.metawait.mu.Unlock()
+		cl.metawait.c.Broadcast()
+		return err
+	}
+
+	c := &cl.consumer
+	if !c.consuming() {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.d != nil:
+		if new := c.d.findNewAssignments(); len(new) > 0 {
+			c.assignPartitions(new, assignWithoutInvalidating, c.d.tps, "new assignments from direct consumer")
+		}
+	case c.g != nil:
+		c.g.findNewAssignments()
+	}
+	return nil
+}
+
 func (cl *Client) triggerUpdateMetadata(must bool, why string) bool {
 	if !must {
 		cl.metawait.mu.Lock()
@@ -277,7 +343,7 @@ func (cl *Client) updateMetadata() (retryWhy multiUpdateWhy, err error) {
 			return
 		}
 		consumerSessionStopped = true
-		loads, tps := cl.consumer.stopSession()
+		loads, tps, _ := cl.consumer.stopSession()
 		reloadOffsets.mergeFrom(loads)
 		tpsPrior = tps
 	}
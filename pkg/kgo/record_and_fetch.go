@@ -109,17 +109,26 @@ type Record struct {
 	Attrs RecordAttrs
 
 	// ProducerEpoch is the producer epoch of this message if it was
-	// produced with a producer ID. An epoch and ID of 0 means it was not.
+	// produced with a producer ID. An epoch and ID of -1 means it was not.
 	//
 	// For producing, this is left unset. This will be set by the client
 	// as appropriate.
+	//
+	// For consuming, this is the producer epoch from the header of the
+	// record batch that contained this record. Together with ProducerID
+	// and Attrs.IsTransactional, this can be used to attribute consumed
+	// records to the transactional producer that wrote them; see also
+	// Fetches.EachRecordRun.
 	ProducerEpoch int16
 
-	// ProducerEpoch is the producer ID of this message if it was produced
-	// with a producer ID. An epoch and ID of 0 means it was not.
+	// ProducerID is the producer ID of this message if it was produced
+	// with a producer ID. An epoch and ID of -1 means it was not.
 	//
 	// For producing, this is left unset. This will be set by the client
 	// as appropriate.
+	//
+	// For consuming, this is the producer ID from the header of the record
+	// batch that contained this record.
 	ProducerID int64
 
 	// LeaderEpoch is the leader epoch of the broker at the time this
@@ -513,6 +522,78 @@ func (fs Fetches) Records() []*Record {
 	return rs
 }
 
+// FetchRecordRun is a run of consecutive records in a partition that were
+// produced by the same producer with the same transactional attribute. See
+// Fetches.EachRecordRun.
+type FetchRecordRun struct {
+	// Topic is the topic this run is for.
+	Topic string
+	// Partition is the partition this run is for.
+	Partition int32
+
+	// Transactional is whether the records in this run were produced
+	// within a transaction.
+	Transactional bool
+	// ProducerID is the producer ID of every record in this run.
+	ProducerID int64
+	// ProducerEpoch is the producer epoch of every record in this run.
+	ProducerEpoch int16
+
+	// Records are the records in this run, in offset order. This slice
+	// aliases the partition's Records and must not be appended to.
+	Records []*Record
+}
+
+// EachRecordRun calls fn for each run of consecutive records within each
+// partition, dropping control records. A run ends at any control record
+// (COMMIT or ABORT), or whenever the producer ID, producer epoch, or
+// transactional attribute changes from one record to the next.
+//
+// When consuming with the read committed isolation level, aborted records are
+// never returned, meaning every transactional run is part of a committed
+// transaction. Control records are only returned by the client if
+// KeepControlRecords is used; with that option, this function can be used to
+// reason about transaction boundaries: a transactional run that is followed
+// by a control record in the same fetch is the end of its transaction.
+//
+// Partitions are visited in the same order as EachPartition, and runs within
+// a partition are visited in offset order.
+func (fs Fetches) EachRecordRun(fn func(FetchRecordRun)) {
+	fs.EachPartition(func(p FetchTopicPartition) {
+		var start int
+		flush := func(end int) {
+			if end > start {
+				first := p.Records[start]
+				fn(FetchRecordRun{
+					Topic:         p.Topic,
+					Partition:     p.Partition,
+					Transactional: first.Attrs.IsTransactional(),
+					ProducerID:    first.ProducerID,
+					ProducerEpoch: first.ProducerEpoch,
+					Records:       p.Records[start:end:end],
+				})
+			}
+		}
+		for i, r := range p.Records {
+			if r.Attrs.IsControl() {
+				flush(i)
+				start = i + 1
+				continue
+			}
+			if i > start {
+				first := p.Records[start]
+				if r.ProducerID != first.ProducerID ||
+					r.ProducerEpoch != first.ProducerEpoch ||
+					r.Attrs.IsTransactional() != first.Attrs.IsTransactional() {
+					flush(i)
+					start = i
+				}
+			}
+		}
+		flush(len(p.Records))
+	})
+}
+
 // FetchTopicPartition is similar to FetchTopic, but for an individual
 // partition.
 type FetchTopicPartition struct {
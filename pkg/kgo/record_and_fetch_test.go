@@ -0,0 +1,151 @@
+package kgo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEachRecordRun(t *testing.T) {
+	const (
+		txn  = 0b0001_0000
+		ctrl = 0b0010_0000
+	)
+	type rec struct {
+		offset int64
+		pid    int64
+		epoch  int16
+		attrs  uint8
+	}
+	type run struct {
+		partition int32
+		txn       bool
+		pid       int64
+		epoch     int16
+		offsets   []int64
+	}
+
+	for _, test := range []struct {
+		name       string
+		partitions [][]rec // index is the partition
+		exp        []run
+	}{
+		{
+			name:       "empty partitions",
+			partitions: [][]rec{nil, {}},
+		},
+
+		{
+			name: "one run",
+			partitions: [][]rec{{
+				{0, 1, 0, txn},
+				{1, 1, 0, txn},
+			}},
+			exp: []run{
+				{0, true, 1, 0, []int64{0, 1}},
+			},
+		},
+
+		{
+			name: "control records end runs and are dropped",
+			partitions: [][]rec{{
+				{0, 1, 0, txn},
+				{1, 1, 0, txn | ctrl}, // commit
+				{2, 1, 0, txn},
+				{3, 1, 0, txn | ctrl}, // abort
+				{4, 1, 0, txn | ctrl},
+				{5, 1, 0, txn},
+			}},
+			exp: []run{
+				{0, true, 1, 0, []int64{0}},
+				{0, true, 1, 0, []int64{2}},
+				{0, true, 1, 0, []int64{5}},
+			},
+		},
+
+		{
+			name: "only control records",
+			partitions: [][]rec{{
+				{0, 1, 0, txn | ctrl},
+			}},
+		},
+
+		{
+			name: "producer id and epoch changes",
+			partitions: [][]rec{{
+				{0, 1, 0, txn},
+				{1, 2, 0, txn},
+				{2, 2, 1, txn},
+				{3, 2, 1, txn},
+			}},
+			exp: []run{
+				{0, true, 1, 0, []int64{0}},
+				{0, true, 2, 0, []int64{1}},
+				{0, true, 2, 1, []int64{2, 3}},
+			},
+		},
+
+		{
+			name: "transactional flag changes",
+			partitions: [][]rec{{
+				{0, 1, 0, 0},
+				{1, 1, 0, txn},
+				{2, 1, 0, 0},
+			}},
+			exp: []run{
+				{0, false, 1, 0, []int64{0}},
+				{0, true, 1, 0, []int64{1}},
+				{0, false, 1, 0, []int64{2}},
+			},
+		},
+
+		{
+			name: "runs do not span partitions",
+			partitions: [][]rec{
+				{{0, 1, 0, txn}},
+				{},
+				{{0, 1, 0, txn}, {1, 1, 0, txn}},
+			},
+			exp: []run{
+				{0, true, 1, 0, []int64{0}},
+				{2, true, 1, 0, []int64{0, 1}},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ft := FetchTopic{Topic: "t"}
+			for partition, recs := range test.partitions {
+				fp := FetchPartition{Partition: int32(partition)}
+				for _, r := range recs {
+					fp.Records = append(fp.Records, &Record{
+						Topic:         "t",
+						Partition:     int32(partition),
+						Offset:        r.offset,
+						ProducerID:    r.pid,
+						ProducerEpoch: r.epoch,
+						Attrs:         RecordAttrs{r.attrs},
+					})
+				}
+				ft.Partitions = append(ft.Partitions, fp)
+			}
+			fs := Fetches{{Topics: []FetchTopic{ft}}}
+
+			var got []run
+			fs.EachRecordRun(func(r FetchRecordRun) {
+				if r.Topic != "t" {
+					t.Errorf("got topic %q, exp t", r.Topic)
+				}
+				if len(r.Records) != cap(r.Records) {
+					t.Errorf("run records len %d != cap %d", len(r.Records), cap(r.Records))
+				}
+				var offsets []int64
+				for _, rec := range r.Records {
+					offsets = append(offsets, rec.Offset)
+				}
+				got = append(got, run{r.Partition, r.Transactional, r.ProducerID, r.ProducerEpoch, offsets})
+			})
+			if !reflect.DeepEqual(got, test.exp) {
+				t.Errorf("got runs %v, exp %v", got, test.exp)
+			}
+		})
+	}
+}
@@ -334,8 +334,8 @@ func (s *source) takeBuffered() Fetch {
 	return s.takeBufferedFn(true, usedOffsets.finishUsingAllWithSet)
 }
 
-func (s *source) discardBuffered() {
-	s.takeBufferedFn(false, usedOffsets.finishUsingAll)
+func (s *source) discardBuffered() Fetch {
+	return s.takeBufferedFn(false, usedOffsets.finishUsingAll)
 }
 
 // takeNBuffered takes a limited amount of records from a buffered fetch,
@@ -439,6 +439,12 @@ func (s *source) createReq() *fetchRequest {
 		session: s.session,
 	}
 
+	// If the user stopped consuming (ConsumeContext), we permanently
+	// fetch nothing.
+	if consumeCtx := s.cl.cfg.consumeCtx; consumeCtx != nil && consumeCtx.Err() != nil {
+		return req
+	}
+
 	paused := s.cl.consumer.loadPaused()
 
 	s.cursorsMu.Lock()
@@ -803,7 +809,7 @@ func (s *source) handleReqResp(br *broker, req *fetchRequest, resp *kmsg.FetchRe
 				continue
 			}
 
-			fp := partOffset.processRespPartition(br, resp.Version, rp, s.cl.decompressor, s.cl.cfg.hooks)
+			fp := partOffset.processRespPartition(br, resp.Version, rp, s.cl.decompressor, &s.cl.cfg)
 			if fp.Err != nil {
 				updateMeta = true
 				updateWhy.add(topic, partition, fp.Err)
@@ -917,7 +923,7 @@ func (s *source) handleReqResp(br *broker, req *fetchRequest, resp *kmsg.FetchRe
 
 // processRespPartition processes all records in all potentially compressed
 // batches (or message sets).
-func (o *cursorOffsetNext) processRespPartition(br *broker, version int16, rp *kmsg.FetchResponseTopicPartition, decompressor *decompressor, hooks hooks) FetchPartition {
+func (o *cursorOffsetNext) processRespPartition(br *broker, version int16, rp *kmsg.FetchResponseTopicPartition, decompressor *decompressor, cfg *cfg) FetchPartition {
 	fp := FetchPartition{
 		Partition:        rp.Partition,
 		Err:              kerr.ErrorForCode(rp.ErrorCode),
@@ -1035,13 +1041,13 @@ func (o *cursorOffsetNext) processRespPartition(br *broker, version int16, rp *k
 		case *kmsg.RecordBatch:
 			m.CompressedBytes = len(t.Records) // for record batches, we only track the record batch length
 			m.CompressionType = uint8(t.Attributes) & 0b0000_0111
-			m.NumRecords, m.UncompressedBytes = o.processRecordBatch(&fp, t, aborter, decompressor)
+			m.NumRecords, m.UncompressedBytes = o.processRecordBatch(&fp, t, aborter, decompressor, cfg)
 		}
 
 		if m.UncompressedBytes == 0 {
 			m.UncompressedBytes = m.CompressedBytes
 		}
-		hooks.each(func(h Hook) {
+		cfg.hooks.each(func(h Hook) {
 			if h, ok := h.(HookFetchBatchRead); ok {
 				h.OnFetchBatchRead(br.meta, o.from.topic, o.from.partition, m)
 			}
@@ -1116,6 +1122,7 @@ func (o *cursorOffsetNext) processRecordBatch(
 	batch *kmsg.RecordBatch,
 	aborter aborter,
 	decompressor *decompressor,
+	cfg *cfg,
 ) (int, int) {
 	if batch.Magic != 2 {
 		fp.Err = fmt.Errorf("unknown batch magic %d", batch.Magic)
@@ -1139,6 +1146,22 @@ func (o *cursorOffsetNext) processRecordBatch(
 
 	uncompressedBytes := len(rawRecords)
 
+	if cfg.validateBatch != nil {
+		if err := cfg.validateBatch(o.from.topic, fp.Partition, rawRecords); err != nil {
+			cfg.logger.Log(LogLevelWarn, "skipping record batch that failed validation",
+				"topic", o.from.topic,
+				"partition", fp.Partition,
+				"first_offset", batch.FirstOffset,
+				"last_offset", lastOffset,
+				"err", err,
+			)
+			if o.offset < lastOffset+1 {
+				o.offset = lastOffset + 1
+			}
+			return 0, uncompressedBytes
+		}
+	}
+
 	numRecords := int(batch.NumRecords)
 	krecords := readRawRecords(numRecords, rawRecords)
 
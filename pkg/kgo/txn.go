@@ -73,6 +73,9 @@ type GroupTransactSession struct {
 // foolproof in the event of some extremely unlikely communication patterns and
 // **potentially** could allow duplicates. See this repo's transaction's doc
 // for more details.
+//
+// Unlike a plain group client, if OnPartitionsLost is not set, the session
+// calls OnPartitionsRevoked with the lost partitions.
 func NewGroupTransactSession(opts ...Opt) (*GroupTransactSession, error) {
 	s := &GroupTransactSession{
 		revokedCh: make(chan struct{}),
@@ -343,7 +346,7 @@ func (s *GroupTransactSession) End(ctx context.Context, commit TransactionEndTry
 	// This 200ms is not perfect but it should be well enough time on a
 	// stable cluster. On an unstable cluster, I still expect clients to be
 	// slower than intra-cluster communication, but there is a risk.
-	if kip447 && s.cl.cfg.requireStable {
+	if g := s.cl.consumer.g; kip447 && g != nil && g.requireStable.get() {
 		defer s.failMu.Unlock()
 	} else {
 		defer func() {
@@ -834,7 +837,7 @@ func (g *groupConsumer) commitTxn(
 				reqPartition.Partition = partition
 				reqPartition.Offset = eo.Offset
 				reqPartition.LeaderEpoch = eo.Epoch
-				reqPartition.Metadata = &req.MemberID
+				reqPartition.Metadata = g.commitMetadata(&req.MemberID)
 				reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
 			}
 			req.Topics = append(req.Topics, reqTopic)